@@ -0,0 +1,132 @@
+// Command preprocess-ab compares whisper transcripts of raw and preprocessed
+// audio against reference transcripts, to check whether a preprocessing
+// setting helps on a set of recordings.
+//
+// Every audio or video file in -samples needs a reference transcript next to
+// it with the same name and a .txt extension:
+//
+//	go run ./cmd/preprocess-ab -samples ./samples -preprocess highpass,loudnorm
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	"github.com/lupppig/briefly/media"
+	"github.com/lupppig/briefly/service"
+	"github.com/lupppig/briefly/utils"
+)
+
+func main() {
+	samples := flag.String("samples", "samples", "directory of recordings with .txt reference transcripts")
+	model := flag.String("model", "models/ggml-base.en.bin", "whisper model")
+	steps := flag.String("preprocess", "all", "preprocessing to compare against the raw audio")
+	flag.Parse()
+
+	p, err := media.ParsePreprocess(*steps)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if p.IsZero() {
+		log.Fatal("nothing to compare: -preprocess is empty")
+	}
+
+	wm, err := whisper.New(*model)
+	if err != nil {
+		log.Fatalf("failed to load whisper model: %v", err)
+	}
+	defer wm.Close()
+	s := &service.Service{WhisperModel: wm}
+
+	entries, err := os.ReadDir(*samples)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "sample\tLUFS before\tLUFS after\tWER raw\tWER %s\n", p.Key())
+
+	var n int
+	var rawTotal, procTotal float64
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.EqualFold(filepath.Ext(name), ".txt") {
+			continue
+		}
+		refPath := filepath.Join(*samples, strings.TrimSuffix(name, filepath.Ext(name))+".txt")
+		ref, err := os.ReadFile(refPath)
+		if err != nil {
+			log.Printf("skipping %s: no reference transcript", name)
+			continue
+		}
+
+		res, err := compare(context.Background(), s, filepath.Join(*samples, name), p)
+		if err != nil {
+			log.Printf("skipping %s: %v", name, err)
+			continue
+		}
+		rawWER := utils.WordErrorRate(string(ref), res.raw)
+		procWER := utils.WordErrorRate(string(ref), res.processed)
+		fmt.Fprintf(tw, "%s\t%.1f\t%.1f\t%.1f%%\t%.1f%%\n",
+			name, res.stats.Before.Integrated, res.stats.After.Integrated, rawWER*100, procWER*100)
+
+		n++
+		rawTotal += rawWER
+		procTotal += procWER
+	}
+	if n > 0 {
+		fmt.Fprintf(tw, "mean\t\t\t%.1f%%\t%.1f%%\n", rawTotal/float64(n)*100, procTotal/float64(n)*100)
+	}
+	tw.Flush()
+}
+
+type result struct {
+	raw, processed string
+	stats          service.LoudnessStats
+}
+
+// compare transcribes path once as it is and once preprocessed with p.
+func compare(ctx context.Context, s *service.Service, path string, p media.Preprocess) (*result, error) {
+	dir, err := os.MkdirTemp("", "preprocess-ab-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	var res result
+	for i, v := range []struct {
+		p    media.Preprocess
+		text *string
+		loud **media.Loudness
+	}{
+		{media.Preprocess{}, &res.raw, &res.stats.Before},
+		{p, &res.processed, &res.stats.After},
+	} {
+		wav := filepath.Join(dir, fmt.Sprintf("%d.wav", i))
+		if err := media.PreprocessAudio(ctx, path, wav, v.p); err != nil {
+			return nil, err
+		}
+		if *v.loud, err = media.MeasureLoudness(ctx, wav); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(wav)
+		if err != nil {
+			return nil, err
+		}
+		segs, err := s.TranscribeWAV(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, seg := range segs {
+			*v.text += seg.Text + " "
+		}
+	}
+	return &res, nil
+}
@@ -65,3 +65,68 @@ func (m *MinioClient) ObjectExists(bucket, objectKey string) (bool, error) {
 
 	return true, nil
 }
+
+// NewMultipartUpload starts a multipart upload of objectKey and returns its
+// upload ID. Parts are added with PutPart and must be at least 5 MB, except
+// the last one.
+func (m *MinioClient) NewMultipartUpload(ctx context.Context, bucket, objectKey, contentType string) (string, error) {
+	core := minio.Core{Client: m.MinClient}
+	uploadID, err := core.NewMultipartUpload(ctx, bucket, objectKey, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return "", fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	return uploadID, nil
+}
+
+// PutPart uploads part number partNumber (from 1) and returns its ETag.
+func (m *MinioClient) PutPart(ctx context.Context, bucket, objectKey, uploadID string, partNumber int, r io.Reader, size int64) (string, error) {
+	core := minio.Core{Client: m.MinClient}
+	part, err := core.PutObjectPart(ctx, bucket, objectKey, uploadID, partNumber, r, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+	}
+	return part.ETag, nil
+}
+
+// CompleteMultipartUpload assembles the object from its parts; etags are the
+// ETags of parts 1 to n in order.
+func (m *MinioClient) CompleteMultipartUpload(ctx context.Context, bucket, objectKey, uploadID string, etags []string) error {
+	parts := make([]minio.CompletePart, len(etags))
+	for i, etag := range etags {
+		parts[i] = minio.CompletePart{PartNumber: i + 1, ETag: etag}
+	}
+	core := minio.Core{Client: m.MinClient}
+	if _, err := core.CompleteMultipartUpload(ctx, bucket, objectKey, uploadID, parts, minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+func (m *MinioClient) AbortMultipartUpload(ctx context.Context, bucket, objectKey, uploadID string) error {
+	core := minio.Core{Client: m.MinClient}
+	return core.AbortMultipartUpload(ctx, bucket, objectKey, uploadID)
+}
+
+// CopyObject copies an object within the bucket on the server side.
+func (m *MinioClient) CopyObject(ctx context.Context, bucket, srcKey, dstKey string) error {
+	_, err := m.MinClient.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: bucket, Object: dstKey},
+		minio.CopySrcOptions{Bucket: bucket, Object: srcKey})
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	return nil
+}
+
+// DownloadObject writes an object to a local file without holding it in
+// memory.
+func (m *MinioClient) DownloadObject(ctx context.Context, bucket, objectKey, path string) error {
+	if err := m.MinClient.FGetObject(ctx, bucket, objectKey, path, minio.GetObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to download object: %w", err)
+	}
+	return nil
+}
+
+func (m *MinioClient) RemoveObject(ctx context.Context, bucket, objectKey string) error {
+	return m.MinClient.RemoveObject(ctx, bucket, objectKey, minio.RemoveObjectOptions{})
+}
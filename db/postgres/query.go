@@ -2,59 +2,136 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
-type Youtube struct {
-	ID        string    `db:"id" json:"id"`
-	VideoID   string    `db:"video_id" json:"videoID"`
-	Link      string    `db:"link" json:"link"`
-	AudioPath string    `json:"audio_path" db:"audio_path"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
+// MediaSource is a remote video or audio item, keyed by the provider that
+// handles it and the provider's own ID for it.
+type MediaSource struct {
+	ID         string    `json:"id"`
+	Provider   string    `json:"provider"`
+	ExternalID string    `json:"external_id"`
+	Link       string    `json:"link"`
+	AudioPath  string    `json:"audio_path"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Metadata is the provider's metadata as JSON, nil until it is fetched.
+	Metadata json.RawMessage `json:"metadata,omitempty"`
 }
 
-func (p *PostgresDB) GetOrCreateYoutube(ctx context.Context, videoID, audioPath, link string) (*Youtube, error) {
-	var yt Youtube
-	err := p.Conn.QueryRow(ctx,
-		`SELECT id, video_id, link, audio_path, created_at
-		 FROM youtube
-		 WHERE video_id=$1`, videoID).Scan(
-		&yt.ID, &yt.VideoID, &yt.Link, &yt.AudioPath, &yt.CreatedAt)
+const mediaSourceColumns = `id, provider, external_id, link, COALESCE(audio_path, ''), created_at, metadata`
+
+func scanMediaSource(row pgx.Row, m *MediaSource) error {
+	return row.Scan(&m.ID, &m.Provider, &m.ExternalID, &m.Link, &m.AudioPath, &m.CreatedAt, &m.Metadata)
+}
+
+func (p *PostgresDB) GetOrCreateMediaSource(ctx context.Context, provider, externalID, link string) (*MediaSource, error) {
+	var m MediaSource
+	err := scanMediaSource(p.Conn.QueryRow(ctx,
+		`SELECT `+mediaSourceColumns+`
+		 FROM media_sources
+		 WHERE provider = $1 AND external_id = $2`, provider, externalID), &m)
 	if err == nil {
-		return &yt, nil
+		return &m, nil
 	}
 	if err != pgx.ErrNoRows {
 		return nil, err
 	}
 
-	err = p.Conn.QueryRow(ctx,
-		`INSERT INTO youtube (video_id, link, audio_path)
-		 VALUES ($1, $2, $3)
-		 RETURNING id, video_id, link, audio_path, created_at`,
-		videoID, link, audioPath).Scan(
-		&yt.ID, &yt.VideoID, &yt.Link, &yt.AudioPath, &yt.CreatedAt)
+	err = scanMediaSource(p.Conn.QueryRow(ctx,
+		`INSERT INTO media_sources (provider, external_id, link, audio_path)
+		 VALUES ($1, $2, $3, '')
+		 RETURNING `+mediaSourceColumns,
+		provider, externalID, link), &m)
 	if err != nil {
 		return nil, err
 	}
 
-	return &yt, nil
+	return &m, nil
 }
 
-func (p *PostgresDB) UpdateYoutubeAudioPath(ctx context.Context, videoID, audioPath string) (*Youtube, error) {
-	var yt Youtube
-	err := p.Conn.QueryRow(ctx,
-		`UPDATE youtube
+func (p *PostgresDB) UpdateMediaSourceAudioPath(ctx context.Context, id, audioPath string) (*MediaSource, error) {
+	var m MediaSource
+	err := scanMediaSource(p.Conn.QueryRow(ctx,
+		`UPDATE media_sources
 		 SET audio_path = $1
-		 WHERE video_id = $2
-		 RETURNING id, video_id, link, audio_path, created_at`,
-		audioPath, videoID).Scan(&yt.ID, &yt.VideoID, &yt.Link, &yt.AudioPath, &yt.CreatedAt)
+		 WHERE id = $2
+		 RETURNING `+mediaSourceColumns,
+		audioPath, id), &m)
 	if err != nil {
 		return nil, err
 	}
-	return &yt, nil
+	return &m, nil
+}
+
+func (p *PostgresDB) UpdateMediaSourceMetadata(ctx context.Context, id string, metadata json.RawMessage) error {
+	_, err := p.Conn.Exec(ctx,
+		`UPDATE media_sources SET metadata = $2 WHERE id = $1`,
+		id, metadata)
+	return err
+}
+
+type WebPage struct {
+	ID          string     `json:"id"`
+	URL         string     `json:"url"`
+	FinalURL    *string    `json:"final_url,omitempty"`
+	ETag        *string    `json:"etag,omitempty"`
+	ContentHash *string    `json:"content_hash,omitempty"`
+	MimeType    *string    `json:"mime_type,omitempty"`
+	Title       *string    `json:"title,omitempty"`
+	FetchedAt   *time.Time `json:"fetched_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+const webPageColumns = `id, url, final_url, etag, content_hash, mime_type, title, fetched_at, created_at`
+
+func scanWebPage(row pgx.Row, w *WebPage) error {
+	return row.Scan(&w.ID, &w.URL, &w.FinalURL, &w.ETag, &w.ContentHash, &w.MimeType, &w.Title, &w.FetchedAt, &w.CreatedAt)
+}
+
+// GetOrCreateWebPage looks a page up by its normalized URL, creating an empty
+// row the first time the URL is seen.
+func (p *PostgresDB) GetOrCreateWebPage(ctx context.Context, url string) (*WebPage, error) {
+	var w WebPage
+	err := scanWebPage(p.Conn.QueryRow(ctx,
+		`SELECT `+webPageColumns+`
+		 FROM web_pages
+		 WHERE url = $1`, url), &w)
+	if err == nil {
+		return &w, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, err
+	}
+
+	err = scanWebPage(p.Conn.QueryRow(ctx,
+		`INSERT INTO web_pages (url)
+		 VALUES ($1)
+		 RETURNING `+webPageColumns,
+		url), &w)
+	if err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+// UpdateWebPageFetch records what the last successful fetch returned.
+func (p *PostgresDB) UpdateWebPageFetch(ctx context.Context, id, finalURL, etag, contentHash, mimeType, title string) (*WebPage, error) {
+	var w WebPage
+	err := scanWebPage(p.Conn.QueryRow(ctx,
+		`UPDATE web_pages
+		 SET final_url = $2, etag = NULLIF($3, ''), content_hash = $4, mime_type = $5, title = NULLIF($6, ''), fetched_at = NOW()
+		 WHERE id = $1
+		 RETURNING `+webPageColumns,
+		id, finalURL, etag, contentHash, mimeType, title), &w)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
 }
 
 type DocumentAudio struct {
@@ -67,14 +144,19 @@ type DocumentAudio struct {
 	Size            int64     `json:"size"`
 	DurationSeconds *float64  `json:"duration_seconds,omitempty"`
 	PageCount       *int      `json:"page_count,omitempty"`
+	SampleRate      *int      `json:"sample_rate,omitempty"`
+	Channels        *int      `json:"channels,omitempty"`
+	Codec           *string   `json:"codec,omitempty"`
+	Bitrate         *int64    `json:"bitrate,omitempty"`
 	CreatedAt       time.Time `json:"created_at,omitempty"`
 }
 
 func (p *PostgresDB) GetOrCreateDocument(ctx context.Context, doc DocumentAudio) (*DocumentAudio, error) {
 	query := `
 	INSERT INTO uploaded_files (
-		file_type, original_name, storage_path, mime_type, size, file_hash, duration_seconds, page_count
-	) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+		file_type, original_name, storage_path, mime_type, size, file_hash, duration_seconds, page_count,
+		sample_rate, channels, codec, bitrate
+	) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
 	ON CONFLICT (file_hash) DO UPDATE
 	SET 
 		file_type = EXCLUDED.file_type,
@@ -83,8 +165,13 @@ func (p *PostgresDB) GetOrCreateDocument(ctx context.Context, doc DocumentAudio)
 		mime_type = EXCLUDED.mime_type,
 		size = EXCLUDED.size,
 		duration_seconds = EXCLUDED.duration_seconds,
-		page_count = EXCLUDED.page_count
-	RETURNING id, file_type, original_name, storage_path, mime_type, size, file_hash, duration_seconds, page_count, created_at;
+		page_count = EXCLUDED.page_count,
+		sample_rate = EXCLUDED.sample_rate,
+		channels = EXCLUDED.channels,
+		codec = EXCLUDED.codec,
+		bitrate = EXCLUDED.bitrate
+	RETURNING id, file_type, original_name, storage_path, mime_type, size, file_hash, duration_seconds, page_count,
+		sample_rate, channels, codec, bitrate, created_at;
 	`
 
 	var result DocumentAudio
@@ -97,6 +184,10 @@ func (p *PostgresDB) GetOrCreateDocument(ctx context.Context, doc DocumentAudio)
 		doc.FileHash,
 		doc.DurationSeconds,
 		doc.PageCount,
+		doc.SampleRate,
+		doc.Channels,
+		doc.Codec,
+		doc.Bitrate,
 	).Scan(
 		&result.ID,
 		&result.FileType,
@@ -107,6 +198,10 @@ func (p *PostgresDB) GetOrCreateDocument(ctx context.Context, doc DocumentAudio)
 		&result.FileHash,
 		&result.DurationSeconds,
 		&result.PageCount,
+		&result.SampleRate,
+		&result.Channels,
+		&result.Codec,
+		&result.Bitrate,
 		&result.CreatedAt,
 	)
 	if err != nil {
@@ -116,33 +211,117 @@ func (p *PostgresDB) GetOrCreateDocument(ctx context.Context, doc DocumentAudio)
 	return &result, nil
 }
 
+// GetDocument returns the uploaded file with the given ID, or nil if there is
+// none.
+func (p *PostgresDB) GetDocument(ctx context.Context, id string) (*DocumentAudio, error) {
+	var d DocumentAudio
+	err := p.Conn.QueryRow(ctx, `
+	SELECT id, file_type, original_name, storage_path, mime_type, size, file_hash, duration_seconds, page_count,
+		sample_rate, channels, codec, bitrate, created_at
+	FROM uploaded_files WHERE id = $1`, id).Scan(
+		&d.ID,
+		&d.FileType,
+		&d.Name,
+		&d.StoragePath,
+		&d.MimeType,
+		&d.Size,
+		&d.FileHash,
+		&d.DurationSeconds,
+		&d.PageCount,
+		&d.SampleRate,
+		&d.Channels,
+		&d.Codec,
+		&d.Bitrate,
+		&d.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
 type SummaryContent struct {
-	Id        string  `json:"id"`
-	Content   string  `json:"content"`
-	AiSummary string  `json:"ai_summary"`
-	FileID    *string `json:"file_id,omitempty"`
-	YoutubeId *string `json:"y_id,omitempty"`
+	Id        string           `json:"id"`
+	Content   string           `json:"content"`
+	AiSummary string           `json:"ai_summary"`
+	FileID    *string          `json:"file_id,omitempty"`
+	SourceID  *string          `json:"source_id,omitempty"`
+	WebPageID *string          `json:"web_page_id,omitempty"`
+	ParentID  *string          `json:"parent_id,omitempty"`
+	Title     *string          `json:"title,omitempty"`
+	Position  *int             `json:"position,omitempty"`
+	Children  []SummaryContent `json:"children,omitempty"`
+	// OCRPages is filled in for scanned documents and images.
+	OCRPages      []OCRPage `json:"ocr_pages,omitempty"`
+	LowQualityOCR bool      `json:"low_quality_ocr,omitempty"`
+	// TableCount is the number of tables stored for the file; fetch them from
+	// /api/files/{file_id}/tables.
+	TableCount int `json:"table_count,omitempty"`
+	// CollectionID is set on the combined summary of a playlist or batch.
+	CollectionID *string `json:"collection_id,omitempty"`
+	// Range is set when only part of a recording was summarized, and
+	// Segments holds its transcript with timestamps in the original media.
+	Range    *ContentRange       `json:"range,omitempty"`
+	Segments []TranscriptSegment `json:"segments,omitempty"`
+	// Metadata is what the provider reports about a media source, such as
+	// its title, channel, chapters and thumbnail.
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+	// Timeline pairs keyframes of a video with the transcript spoken while
+	// they were on screen; it is only built when keyframes are asked for.
+	Timeline []TimelineEntry `json:"timeline,omitempty"`
+	// Preprocess names the filters the audio went through before it was
+	// transcribed, and Loudness holds its loudness before and after them.
+	Preprocess string          `json:"preprocess,omitempty"`
+	Loudness   json.RawMessage `json:"loudness,omitempty"`
+	// Transcriber is what produced the transcript: "whisper", "gemini" or
+	// "captions". It is empty for documents.
+	Transcriber string `json:"transcriber,omitempty"`
+}
+
+// ContentRange is a time range in seconds; a nil End runs to the end of the
+// recording.
+type ContentRange struct {
+	Start float64  `json:"start"`
+	End   *float64 `json:"end,omitempty"`
+}
+
+type TranscriptSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
 }
 
-func (p *PostgresDB) CreateContent(ctx context.Context, content string, aiSummary string, fileID, yID *string) (*SummaryContent, error) {
-	summ := &SummaryContent{
-		Content:   content,
-		AiSummary: aiSummary,
-		FileID:    fileID,
-		YoutubeId: yID,
+const contentColumns = `id, contents, ai_summary, file_id, source_id, web_page_id, collection_id, parent_id, title, position, range_start, range_end, preprocess, loudness, transcriber`
+
+func scanContent(row pgx.Row, c *SummaryContent) error {
+	var rangeStart, rangeEnd *float64
+	err := row.Scan(&c.Id, &c.Content, &c.AiSummary, &c.FileID, &c.SourceID, &c.WebPageID, &c.CollectionID, &c.ParentID, &c.Title, &c.Position, &rangeStart, &rangeEnd, &c.Preprocess, &c.Loudness, &c.Transcriber)
+	if err == nil && rangeStart != nil {
+		c.Range = &ContentRange{Start: *rangeStart, End: rangeEnd}
 	}
+	return err
+}
 
-	err := p.Conn.QueryRow(ctx, `
-		INSERT INTO contents(contents, ai_summary, file_id, youtube_id)
+// rangeArgs turns a range into the range_start/range_end query arguments;
+// nil means the whole recording.
+func rangeArgs(r *ContentRange) (start, end *float64) {
+	if r == nil {
+		return nil, nil
+	}
+	return &r.Start, r.End
+}
+
+func (p *PostgresDB) CreateContent(ctx context.Context, content string, aiSummary string, fileID, sourceID *string) (*SummaryContent, error) {
+	summ := &SummaryContent{}
+
+	err := scanContent(p.Conn.QueryRow(ctx, `
+		INSERT INTO contents(contents, ai_summary, file_id, source_id)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, contents, ai_summary, file_id, youtube_id
-	`, content, aiSummary, fileID, yID).Scan(
-		&summ.Id,
-		&summ.Content,
-		&summ.AiSummary,
-		&summ.FileID,
-		&summ.YoutubeId,
-	)
+		RETURNING `+contentColumns,
+		content, aiSummary, fileID, sourceID), summ)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create content: %w", err)
@@ -151,16 +330,243 @@ func (p *PostgresDB) CreateContent(ctx context.Context, content string, aiSummar
 	return summ, nil
 }
 
-func (p *PostgresDB) GetContentByYoutubeID(ctx context.Context, ytID string) (*SummaryContent, error) {
-	var c SummaryContent
+// UpdateContent fills in the text and summary of a content item that was
+// created before they were known, such as a book whose chapters are stored
+// as they are summarized.
+func (p *PostgresDB) UpdateContent(ctx context.Context, id, content, aiSummary string) error {
+	_, err := p.Conn.Exec(ctx,
+		`UPDATE contents SET contents = $2, ai_summary = $3 WHERE id = $1`,
+		id, content, aiSummary)
+	return err
+}
 
+// MediaContent is the transcript and summary of a recording, or part of one.
+type MediaContent struct {
+	Content   string
+	AiSummary string
+	FileID    *string
+	SourceID  *string
+	Range     *ContentRange
+	Segments  []TranscriptSegment
+	// Preprocess, Loudness and Transcriber are stored as given; see
+	// SummaryContent.
+	Preprocess  string
+	Loudness    json.RawMessage
+	Transcriber string
+	// Title names recordings that have no file or source to go by.
+	Title string
+}
+
+// CreateMediaContent stores a transcript summary together with its timed
+// segments.
+func (p *PostgresDB) CreateMediaContent(ctx context.Context, m MediaContent) (*SummaryContent, error) {
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	start, end := rangeArgs(m.Range)
+	summ := &SummaryContent{}
+	err = scanContent(tx.QueryRow(ctx, `
+		INSERT INTO contents(contents, ai_summary, file_id, source_id, range_start, range_end, preprocess, loudness, title, transcriber)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)
+		RETURNING `+contentColumns,
+		m.Content, m.AiSummary, m.FileID, m.SourceID, start, end, m.Preprocess, m.Loudness, m.Title, m.Transcriber), summ)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create content: %w", err)
+	}
+
+	for i, seg := range m.Segments {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO transcript_segments (content_id, position, start_seconds, end_seconds, text)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			summ.Id, i+1, seg.Start, seg.End, seg.Text); err != nil {
+			return nil, fmt.Errorf("failed to save transcript segment: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	summ.Segments = m.Segments
+	return summ, nil
+}
+
+// Keyframe is a still taken from a video at a scene change. Time is in
+// seconds of the original recording.
+type Keyframe struct {
+	ID        string  `json:"id"`
+	ContentID string  `json:"content_id"`
+	Time      float64 `json:"time_seconds"`
+	ObjectKey string  `json:"-"`
+	Caption   *string `json:"caption,omitempty"`
+}
+
+// TimelineEntry is one keyframe with the transcript from its time until the
+// next keyframe.
+type TimelineEntry struct {
+	Start    float64 `json:"start"`
+	End      float64 `json:"end,omitempty"`
+	ImageURL string  `json:"image_url"`
+	Caption  *string `json:"caption,omitempty"`
+	Text     string  `json:"text,omitempty"`
+}
+
+// SaveKeyframes stores the keyframes of a content item and returns them with
+// their IDs.
+func (p *PostgresDB) SaveKeyframes(ctx context.Context, contentID string, frames []Keyframe) ([]Keyframe, error) {
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	saved := make([]Keyframe, 0, len(frames))
+	for _, f := range frames {
+		f.ContentID = contentID
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO keyframes (content_id, time_seconds, object_key, caption)
+			 VALUES ($1, $2, $3, $4)
+			 RETURNING id`,
+			contentID, f.Time, f.ObjectKey, f.Caption).Scan(&f.ID); err != nil {
+			return nil, fmt.Errorf("failed to save keyframe: %w", err)
+		}
+		saved = append(saved, f)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+const keyframeColumns = `id, content_id, time_seconds, object_key, caption`
+
+func (p *PostgresDB) GetKeyframes(ctx context.Context, contentID string) ([]Keyframe, error) {
+	rows, err := p.Conn.Query(ctx,
+		`SELECT `+keyframeColumns+`
+		 FROM keyframes
+		 WHERE content_id = $1
+		 ORDER BY time_seconds`, contentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var frames []Keyframe
+	for rows.Next() {
+		var f Keyframe
+		if err := rows.Scan(&f.ID, &f.ContentID, &f.Time, &f.ObjectKey, &f.Caption); err != nil {
+			return nil, err
+		}
+		frames = append(frames, f)
+	}
+	return frames, rows.Err()
+}
+
+func (p *PostgresDB) GetKeyframe(ctx context.Context, id string) (*Keyframe, error) {
+	var f Keyframe
 	err := p.Conn.QueryRow(ctx,
-		`SELECT id, contents, ai_summary, file_id, youtube_id 
+		`SELECT `+keyframeColumns+` FROM keyframes WHERE id = $1`, id).
+		Scan(&f.ID, &f.ContentID, &f.Time, &f.ObjectKey, &f.Caption)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (p *PostgresDB) UpdateKeyframeCaption(ctx context.Context, id, caption string) error {
+	_, err := p.Conn.Exec(ctx,
+		`UPDATE keyframes SET caption = $2 WHERE id = $1`, id, caption)
+	return err
+}
+
+func (p *PostgresDB) GetTranscriptSegments(ctx context.Context, contentID string) ([]TranscriptSegment, error) {
+	rows, err := p.Conn.Query(ctx,
+		`SELECT start_seconds, end_seconds, text
+		 FROM transcript_segments
+		 WHERE content_id = $1
+		 ORDER BY position`, contentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var segs []TranscriptSegment
+	for rows.Next() {
+		var seg TranscriptSegment
+		if err := rows.Scan(&seg.Start, &seg.End, &seg.Text); err != nil {
+			return nil, err
+		}
+		segs = append(segs, seg)
+	}
+	return segs, rows.Err()
+}
+
+// CreateChildContent stores one part (a chapter, a slide range...) of a larger
+// content item. Children share the parent's file/media source/web page link so they
+// can be found from either side.
+func (p *PostgresDB) CreateChildContent(ctx context.Context, parent *SummaryContent, title string, position int, content, aiSummary string) (*SummaryContent, error) {
+	summ := &SummaryContent{}
+
+	err := scanContent(p.Conn.QueryRow(ctx, `
+		INSERT INTO contents(contents, ai_summary, file_id, source_id, web_page_id, collection_id, parent_id, title, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+contentColumns,
+		content, aiSummary, parent.FileID, parent.SourceID, parent.WebPageID, parent.CollectionID, parent.Id, title, position), summ)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create child content: %w", err)
+	}
+
+	return summ, nil
+}
+
+func (p *PostgresDB) GetChildContents(ctx context.Context, parentID string) ([]SummaryContent, error) {
+	rows, err := p.Conn.Query(ctx,
+		`SELECT `+contentColumns+`
+		 FROM contents
+		 WHERE parent_id = $1
+		 ORDER BY position`,
+		parentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var children []SummaryContent
+	for rows.Next() {
+		var c SummaryContent
+		if err := scanContent(rows, &c); err != nil {
+			return nil, err
+		}
+		children = append(children, c)
+	}
+
+	return children, rows.Err()
+}
+
+// GetContentBySourceID returns the summary of a media source for the given
+// range and preprocessing, made by any of transcribers; nil asks for the
+// summary of the whole recording and "" for the unprocessed audio.
+func (p *PostgresDB) GetContentBySourceID(ctx context.Context, sourceID string, r *ContentRange, preprocess string, transcribers []string) (*SummaryContent, error) {
+	var c SummaryContent
+
+	start, end := rangeArgs(r)
+	err := scanContent(p.Conn.QueryRow(ctx,
+		`SELECT `+contentColumns+`
 		 FROM contents 
-		 WHERE youtube_id = $1 
+		 WHERE source_id = $1 AND parent_id IS NULL
+		   AND range_start IS NOT DISTINCT FROM $2 AND range_end IS NOT DISTINCT FROM $3
+		   AND preprocess = $4 AND transcriber = ANY($5)
 		 LIMIT 1`,
-		ytID,
-	).Scan(&c.Id, &c.Content, &c.AiSummary, &c.FileID, &c.YoutubeId)
+		sourceID, start, end, preprocess, transcribers,
+	), &c)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -172,16 +578,179 @@ func (p *PostgresDB) GetContentByYoutubeID(ctx context.Context, ytID string) (*S
 	return &c, nil
 }
 
-func (p *PostgresDB) GetContentByDocID(ctx context.Context, dID string) (*SummaryContent, error) {
+// GetContentByDocID returns the summary of an uploaded file for the given
+// range, preprocessing and transcriber; documents are always looked up with a
+// nil range and "" for both.
+func (p *PostgresDB) GetContentByDocID(ctx context.Context, dID string, r *ContentRange, preprocess, transcriber string) (*SummaryContent, error) {
 	var c SummaryContent
 
-	err := p.Conn.QueryRow(ctx,
-		`SELECT id, contents, ai_summary, file_id, youtube_id 
+	start, end := rangeArgs(r)
+	err := scanContent(p.Conn.QueryRow(ctx,
+		`SELECT `+contentColumns+`
 		 FROM contents 
-		 WHERE file_id = $1 
+		 WHERE file_id = $1 AND parent_id IS NULL
+		   AND range_start IS NOT DISTINCT FROM $2 AND range_end IS NOT DISTINCT FROM $3
+		   AND preprocess = $4 AND transcriber = $5
+		 LIMIT 1`,
+		dID, start, end, preprocess, transcriber,
+	), &c)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+type OCRPage struct {
+	PageNumber int     `json:"page"`
+	Confidence float64 `json:"confidence"`
+	LowQuality bool    `json:"low_quality"`
+}
+
+func (p *PostgresDB) SaveOCRPages(ctx context.Context, fileID string, pages []OCRPage) error {
+	for _, pg := range pages {
+		_, err := p.Conn.Exec(ctx, `
+			INSERT INTO ocr_pages(file_id, page_number, confidence, low_quality)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (file_id, page_number) DO UPDATE SET
+				confidence = EXCLUDED.confidence,
+				low_quality = EXCLUDED.low_quality`,
+			fileID, pg.PageNumber, pg.Confidence, pg.LowQuality)
+		if err != nil {
+			return fmt.Errorf("failed to save ocr page %d: %w", pg.PageNumber, err)
+		}
+	}
+	return nil
+}
+
+func (p *PostgresDB) GetOCRPages(ctx context.Context, fileID string) ([]OCRPage, error) {
+	rows, err := p.Conn.Query(ctx,
+		`SELECT page_number, confidence, low_quality
+		 FROM ocr_pages
+		 WHERE file_id = $1
+		 ORDER BY page_number`,
+		fileID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pages []OCRPage
+	for rows.Next() {
+		var pg OCRPage
+		if err := rows.Scan(&pg.PageNumber, &pg.Confidence, &pg.LowQuality); err != nil {
+			return nil, err
+		}
+		pages = append(pages, pg)
+	}
+
+	return pages, rows.Err()
+}
+
+// ContentTable is a table pulled out of an uploaded file. Rows holds the cells
+// as text, header row first.
+type ContentTable struct {
+	ID       string     `json:"id"`
+	FileID   string     `json:"file_id"`
+	Position int        `json:"position"`
+	Title    *string    `json:"title,omitempty"`
+	Page     *int       `json:"page,omitempty"`
+	Rows     [][]string `json:"rows"`
+}
+
+// ReplaceContentTables swaps the stored tables of a file for a new set, so a
+// re-extraction never leaves stale rows behind.
+func (p *PostgresDB) ReplaceContentTables(ctx context.Context, fileID string, tables []ContentTable) error {
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM content_tables WHERE file_id = $1`, fileID); err != nil {
+		return fmt.Errorf("failed to clear tables: %w", err)
+	}
+	for _, t := range tables {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO content_tables(file_id, position, title, page, rows)
+			VALUES ($1, $2, $3, $4, $5)`,
+			fileID, t.Position, t.Title, t.Page, t.Rows)
+		if err != nil {
+			return fmt.Errorf("failed to save table %d: %w", t.Position, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+const tableColumns = `id, file_id, position, title, page, rows`
+
+func (p *PostgresDB) GetContentTables(ctx context.Context, fileID string) ([]ContentTable, error) {
+	rows, err := p.Conn.Query(ctx,
+		`SELECT `+tableColumns+`
+		 FROM content_tables
+		 WHERE file_id = $1
+		 ORDER BY position`,
+		fileID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []ContentTable
+	for rows.Next() {
+		var t ContentTable
+		if err := rows.Scan(&t.ID, &t.FileID, &t.Position, &t.Title, &t.Page, &t.Rows); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+
+	return tables, rows.Err()
+}
+
+func (p *PostgresDB) GetContentTable(ctx context.Context, fileID string, position int) (*ContentTable, error) {
+	var t ContentTable
+
+	err := p.Conn.QueryRow(ctx,
+		`SELECT `+tableColumns+`
+		 FROM content_tables
+		 WHERE file_id = $1 AND position = $2`,
+		fileID, position,
+	).Scan(&t.ID, &t.FileID, &t.Position, &t.Title, &t.Page, &t.Rows)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (p *PostgresDB) CountContentTables(ctx context.Context, fileID string) (int, error) {
+	var n int
+	err := p.Conn.QueryRow(ctx, `SELECT COUNT(*) FROM content_tables WHERE file_id = $1`, fileID).Scan(&n)
+	return n, err
+}
+
+func (p *PostgresDB) GetContentByWebPageID(ctx context.Context, pageID string) (*SummaryContent, error) {
+	var c SummaryContent
+
+	err := scanContent(p.Conn.QueryRow(ctx,
+		`SELECT `+contentColumns+`
+		 FROM contents
+		 WHERE web_page_id = $1 AND parent_id IS NULL
 		 LIMIT 1`,
-		dID,
-	).Scan(&c.Id, &c.Content, &c.AiSummary, &c.FileID, &c.YoutubeId)
+		pageID,
+	), &c)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -192,3 +761,664 @@ func (p *PostgresDB) GetContentByDocID(ctx context.Context, dID string) (*Summar
 
 	return &c, nil
 }
+
+// ReplaceWebPageContent stores the summary of a page, dropping the summary of
+// an earlier version of it.
+func (p *PostgresDB) ReplaceWebPageContent(ctx context.Context, pageID, content, aiSummary string) (*SummaryContent, error) {
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM contents WHERE web_page_id = $1`, pageID); err != nil {
+		return nil, fmt.Errorf("failed to clear page content: %w", err)
+	}
+
+	summ := &SummaryContent{}
+	err = scanContent(tx.QueryRow(ctx, `
+		INSERT INTO contents(contents, ai_summary, web_page_id)
+		VALUES ($1, $2, $3)
+		RETURNING `+contentColumns,
+		content, aiSummary, pageID), summ)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create content: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return summ, nil
+}
+
+// Collection groups content items that were processed together, such as the
+// videos of a playlist. Its combined summary is a content row with
+// collection_id set.
+type Collection struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Source    string    `json:"source"`
+	Title     *string   `json:"title,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (p *PostgresDB) CreateCollection(ctx context.Context, kind, source, title string) (*Collection, error) {
+	var c Collection
+	err := p.Conn.QueryRow(ctx,
+		`INSERT INTO collections (kind, source, title)
+		 VALUES ($1, $2, NULLIF($3, ''))
+		 RETURNING id, kind, source, title, created_at`,
+		kind, source, title).Scan(&c.ID, &c.Kind, &c.Source, &c.Title, &c.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+	return &c, nil
+}
+
+// AddCollectionItems lists content items under a collection in the given
+// order; items already in it keep their position.
+func (p *PostgresDB) AddCollectionItems(ctx context.Context, collectionID string, contentIDs []string) error {
+	_, err := p.Conn.Exec(ctx,
+		`INSERT INTO collection_items (collection_id, content_id, position)
+		 SELECT $1, t.id, t.position
+		 FROM unnest($2::uuid[]) WITH ORDINALITY AS t(id, position)
+		 ON CONFLICT DO NOTHING`,
+		collectionID, contentIDs)
+	if err != nil {
+		return fmt.Errorf("failed to save collection items: %w", err)
+	}
+	return nil
+}
+
+// SaveCollectionDigest stores the combined summary of a collection together
+// with the ordered list of content items it was built from.
+func (p *PostgresDB) SaveCollectionDigest(ctx context.Context, collectionID string, contentIDs []string, content, aiSummary string) (*SummaryContent, error) {
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	for i, id := range contentIDs {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO collection_items (collection_id, content_id, position)
+			 VALUES ($1, $2, $3)
+			 ON CONFLICT DO NOTHING`,
+			collectionID, id, i+1); err != nil {
+			return nil, fmt.Errorf("failed to save collection item: %w", err)
+		}
+	}
+
+	summ := &SummaryContent{}
+	err = scanContent(tx.QueryRow(ctx, `
+		INSERT INTO contents(contents, ai_summary, collection_id)
+		VALUES ($1, $2, $3)
+		RETURNING `+contentColumns,
+		content, aiSummary, collectionID), summ)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create content: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return summ, nil
+}
+
+// Feed is a podcast subscription.
+type Feed struct {
+	ID           string     `json:"id"`
+	URL          string     `json:"url"`
+	Title        *string    `json:"title,omitempty"`
+	Link         *string    `json:"link,omitempty"`
+	ETag         *string    `json:"-"`
+	LastPolledAt *time.Time `json:"last_polled_at,omitempty"`
+	LastError    *string    `json:"last_error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+const feedColumns = `id, url, title, link, etag, last_polled_at, last_error, created_at`
+
+func scanFeed(row pgx.Row, f *Feed) error {
+	return row.Scan(&f.ID, &f.URL, &f.Title, &f.Link, &f.ETag, &f.LastPolledAt, &f.LastError, &f.CreatedAt)
+}
+
+// CreateFeed registers a feed URL along with the items it already lists, in
+// one transaction, so a feed never exists without its initial items. created
+// is false when the feed was already registered, in which case the existing
+// row is returned untouched and items are ignored.
+func (p *PostgresDB) CreateFeed(ctx context.Context, url, title, link, etag string, items []FeedItem) (feed *Feed, created bool, err error) {
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var f Feed
+	err = scanFeed(tx.QueryRow(ctx,
+		`INSERT INTO feeds (url, title, link, etag, last_polled_at)
+		 VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), NOW())
+		 ON CONFLICT (url) DO NOTHING
+		 RETURNING `+feedColumns,
+		url, title, link, etag), &f)
+	if err == pgx.ErrNoRows {
+		err = scanFeed(tx.QueryRow(ctx,
+			`SELECT `+feedColumns+` FROM feeds WHERE url = $1`, url), &f)
+		if err != nil {
+			return nil, false, err
+		}
+		return &f, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create feed: %w", err)
+	}
+
+	if _, err := insertFeedItems(ctx, tx, f.ID, items); err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, err
+	}
+	return &f, true, nil
+}
+
+func (p *PostgresDB) GetFeed(ctx context.Context, id string) (*Feed, error) {
+	var f Feed
+	err := scanFeed(p.Conn.QueryRow(ctx,
+		`SELECT `+feedColumns+` FROM feeds WHERE id = $1`, id), &f)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (p *PostgresDB) ListFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := p.Conn.Query(ctx,
+		`SELECT `+feedColumns+` FROM feeds ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var feeds []Feed
+	for rows.Next() {
+		var f Feed
+		if err := scanFeed(rows, &f); err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+// UpdateFeedPoll records the outcome of a poll. An empty errMsg clears the
+// last error; title and etag are only overwritten when given.
+func (p *PostgresDB) UpdateFeedPoll(ctx context.Context, id, title, etag, errMsg string) error {
+	_, err := p.Conn.Exec(ctx,
+		`UPDATE feeds
+		 SET title = COALESCE(NULLIF($2, ''), title),
+		     etag = COALESCE(NULLIF($3, ''), etag),
+		     last_error = NULLIF($4, ''),
+		     last_polled_at = NOW()
+		 WHERE id = $1`,
+		id, title, etag, errMsg)
+	return err
+}
+
+// Statuses of feed episodes and watched channel videos. Items seen when a
+// subscription is created are skipped unless they fall into the requested
+// backfill.
+const (
+	ItemSkipped    = "skipped"
+	ItemPending    = "pending"
+	ItemProcessing = "processing"
+	ItemDone       = "done"
+	ItemError      = "error"
+)
+
+type FeedItem struct {
+	ID           string     `json:"id"`
+	FeedID       string     `json:"feed_id"`
+	GUID         string     `json:"guid"`
+	Title        *string    `json:"title,omitempty"`
+	Link         *string    `json:"link,omitempty"`
+	EnclosureURL string     `json:"enclosure_url"`
+	PublishedAt  *time.Time `json:"published_at,omitempty"`
+	Status       string     `json:"status"`
+	Error        *string    `json:"error,omitempty"`
+	ContentID    *string    `json:"content_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	// AiSummary is joined in from the item's content when listing.
+	AiSummary *string `json:"ai_summary,omitempty"`
+}
+
+const feedItemColumns = `i.id, i.feed_id, i.guid, i.title, i.link, i.enclosure_url, i.published_at, i.status, i.error, i.content_id, i.created_at, c.ai_summary`
+
+func scanFeedItem(row pgx.Row, it *FeedItem) error {
+	return row.Scan(&it.ID, &it.FeedID, &it.GUID, &it.Title, &it.Link, &it.EnclosureURL, &it.PublishedAt, &it.Status, &it.Error, &it.ContentID, &it.CreatedAt, &it.AiSummary)
+}
+
+// InsertFeedItems adds the items not seen before and returns how many were
+// new.
+func (p *PostgresDB) InsertFeedItems(ctx context.Context, feedID string, items []FeedItem) (int, error) {
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	added, err := insertFeedItems(ctx, tx, feedID, items)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+func insertFeedItems(ctx context.Context, tx pgx.Tx, feedID string, items []FeedItem) (int, error) {
+	added := 0
+	for _, it := range items {
+		tag, err := tx.Exec(ctx,
+			`INSERT INTO feed_items (feed_id, guid, title, link, enclosure_url, published_at, status)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)
+			 ON CONFLICT (feed_id, guid) DO NOTHING`,
+			feedID, it.GUID, it.Title, it.Link, it.EnclosureURL, it.PublishedAt, it.Status)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save feed item: %w", err)
+		}
+		added += int(tag.RowsAffected())
+	}
+	return added, nil
+}
+
+func (p *PostgresDB) queryFeedItems(ctx context.Context, where string, args ...any) ([]FeedItem, error) {
+	rows, err := p.Conn.Query(ctx,
+		`SELECT `+feedItemColumns+`
+		 FROM feed_items i
+		 LEFT JOIN contents c ON c.id = i.content_id
+		 WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []FeedItem
+	for rows.Next() {
+		var it FeedItem
+		if err := scanFeedItem(rows, &it); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// ListFeedItems returns a feed's episodes, newest first.
+func (p *PostgresDB) ListFeedItems(ctx context.Context, feedID string) ([]FeedItem, error) {
+	return p.queryFeedItems(ctx,
+		`i.feed_id = $1 ORDER BY i.published_at DESC NULLS LAST, i.created_at DESC`, feedID)
+}
+
+// PendingFeedItems returns the episodes still to be summarized, oldest first.
+func (p *PostgresDB) PendingFeedItems(ctx context.Context, feedID string) ([]FeedItem, error) {
+	return p.queryFeedItems(ctx,
+		`i.feed_id = $1 AND i.status = $2 ORDER BY i.published_at NULLS FIRST, i.created_at`, feedID, ItemPending)
+}
+
+func (p *PostgresDB) UpdateFeedItemStatus(ctx context.Context, id, status, errMsg string, contentID *string) error {
+	_, err := p.Conn.Exec(ctx,
+		`UPDATE feed_items
+		 SET status = $2, error = NULLIF($3, ''), content_id = COALESCE($4, content_id)
+		 WHERE id = $1`,
+		id, status, errMsg, contentID)
+	return err
+}
+
+// RequeueProcessingFeedItems puts items that were being processed when the
+// service stopped back in the queue.
+func (p *PostgresDB) RequeueProcessingFeedItems(ctx context.Context) error {
+	_, err := p.Conn.Exec(ctx,
+		`UPDATE feed_items SET status = $1 WHERE status = $2`,
+		ItemPending, ItemProcessing)
+	return err
+}
+
+// ChannelWatch is a YouTube channel whose new uploads are summarized as they
+// appear. ChannelKey is the channel path, such as "@handle", so differently
+// shaped links to one channel share a watch.
+type ChannelWatch struct {
+	ID            string     `json:"id"`
+	ChannelKey    string     `json:"channel"`
+	URL           string     `json:"url"`
+	Title         *string    `json:"title,omitempty"`
+	IntervalSecs  int        `json:"check_interval_seconds"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+	LastError     *string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+const channelWatchColumns = `id, channel_key, url, title, check_interval_seconds, last_checked_at, last_error, created_at`
+
+func scanChannelWatch(row pgx.Row, cw *ChannelWatch) error {
+	return row.Scan(&cw.ID, &cw.ChannelKey, &cw.URL, &cw.Title, &cw.IntervalSecs, &cw.LastCheckedAt, &cw.LastError, &cw.CreatedAt)
+}
+
+// CreateChannelWatch registers a channel along with the videos already on
+// it, in one transaction, so a watch never exists without its initial
+// videos. created is false when the channel was already watched, in which
+// case the existing row is returned untouched and videos are ignored.
+func (p *PostgresDB) CreateChannelWatch(ctx context.Context, key, url, title string, intervalSecs int, videos []WatchVideo) (watch *ChannelWatch, created bool, err error) {
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var cw ChannelWatch
+	err = scanChannelWatch(tx.QueryRow(ctx,
+		`INSERT INTO channel_watches (channel_key, url, title, check_interval_seconds, last_checked_at)
+		 VALUES ($1, $2, NULLIF($3, ''), $4, NOW())
+		 ON CONFLICT (channel_key) DO NOTHING
+		 RETURNING `+channelWatchColumns,
+		key, url, title, intervalSecs), &cw)
+	if err == pgx.ErrNoRows {
+		err = scanChannelWatch(tx.QueryRow(ctx,
+			`SELECT `+channelWatchColumns+` FROM channel_watches WHERE channel_key = $1`, key), &cw)
+		if err != nil {
+			return nil, false, err
+		}
+		return &cw, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create channel watch: %w", err)
+	}
+
+	if _, err := insertWatchVideos(ctx, tx, cw.ID, videos); err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, err
+	}
+	return &cw, true, nil
+}
+
+func (p *PostgresDB) GetChannelWatch(ctx context.Context, id string) (*ChannelWatch, error) {
+	var cw ChannelWatch
+	err := scanChannelWatch(p.Conn.QueryRow(ctx,
+		`SELECT `+channelWatchColumns+` FROM channel_watches WHERE id = $1`, id), &cw)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &cw, nil
+}
+
+func (p *PostgresDB) queryChannelWatches(ctx context.Context, where string) ([]ChannelWatch, error) {
+	rows, err := p.Conn.Query(ctx,
+		`SELECT `+channelWatchColumns+` FROM channel_watches WHERE `+where+` ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var watches []ChannelWatch
+	for rows.Next() {
+		var cw ChannelWatch
+		if err := scanChannelWatch(rows, &cw); err != nil {
+			return nil, err
+		}
+		watches = append(watches, cw)
+	}
+	return watches, rows.Err()
+}
+
+func (p *PostgresDB) ListChannelWatches(ctx context.Context) ([]ChannelWatch, error) {
+	return p.queryChannelWatches(ctx, `TRUE`)
+}
+
+// DueChannelWatches returns the watches whose check interval has passed
+// since their last check.
+func (p *PostgresDB) DueChannelWatches(ctx context.Context) ([]ChannelWatch, error) {
+	return p.queryChannelWatches(ctx,
+		`last_checked_at IS NULL
+		 OR last_checked_at + check_interval_seconds * INTERVAL '1 second' <= NOW()`)
+}
+
+// UpdateChannelWatchCheck records the outcome of a check. An empty errMsg
+// clears the last error; title is only overwritten when given.
+func (p *PostgresDB) UpdateChannelWatchCheck(ctx context.Context, id, title, errMsg string) error {
+	_, err := p.Conn.Exec(ctx,
+		`UPDATE channel_watches
+		 SET title = COALESCE(NULLIF($2, ''), title),
+		     last_error = NULLIF($3, ''),
+		     last_checked_at = NOW()
+		 WHERE id = $1`,
+		id, title, errMsg)
+	return err
+}
+
+// WatchVideo is a video seen on a watched channel. Its status uses the same
+// values as feed items.
+type WatchVideo struct {
+	ID        string    `json:"id"`
+	WatchID   string    `json:"watch_id"`
+	VideoID   string    `json:"video_id"`
+	Title     *string   `json:"title,omitempty"`
+	Status    string    `json:"status"`
+	Error     *string   `json:"error,omitempty"`
+	ContentID *string   `json:"content_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// AiSummary is joined in from the video's content when listing.
+	AiSummary *string `json:"ai_summary,omitempty"`
+}
+
+const watchVideoColumns = `v.id, v.watch_id, v.video_id, v.title, v.status, v.error, v.content_id, v.created_at, c.ai_summary`
+
+func scanWatchVideo(row pgx.Row, v *WatchVideo) error {
+	return row.Scan(&v.ID, &v.WatchID, &v.VideoID, &v.Title, &v.Status, &v.Error, &v.ContentID, &v.CreatedAt, &v.AiSummary)
+}
+
+// InsertWatchVideos adds the videos not seen before and returns how many were
+// new. Videos are inserted in the order given, so pass them oldest first.
+func (p *PostgresDB) InsertWatchVideos(ctx context.Context, watchID string, videos []WatchVideo) (int, error) {
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	added, err := insertWatchVideos(ctx, tx, watchID, videos)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+func insertWatchVideos(ctx context.Context, tx pgx.Tx, watchID string, videos []WatchVideo) (int, error) {
+	added := 0
+	for _, v := range videos {
+		tag, err := tx.Exec(ctx,
+			`INSERT INTO channel_watch_videos (watch_id, video_id, title, status, created_at)
+			 VALUES ($1, $2, $3, $4, clock_timestamp())
+			 ON CONFLICT (watch_id, video_id) DO NOTHING`,
+			watchID, v.VideoID, v.Title, v.Status)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save channel video: %w", err)
+		}
+		added += int(tag.RowsAffected())
+	}
+	return added, nil
+}
+
+func (p *PostgresDB) queryWatchVideos(ctx context.Context, where string, args ...any) ([]WatchVideo, error) {
+	rows, err := p.Conn.Query(ctx,
+		`SELECT `+watchVideoColumns+`
+		 FROM channel_watch_videos v
+		 LEFT JOIN contents c ON c.id = v.content_id
+		 WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var videos []WatchVideo
+	for rows.Next() {
+		var v WatchVideo
+		if err := scanWatchVideo(rows, &v); err != nil {
+			return nil, err
+		}
+		videos = append(videos, v)
+	}
+	return videos, rows.Err()
+}
+
+// ListWatchVideos returns a watch's videos, newest first.
+func (p *PostgresDB) ListWatchVideos(ctx context.Context, watchID string) ([]WatchVideo, error) {
+	return p.queryWatchVideos(ctx, `v.watch_id = $1 ORDER BY v.created_at DESC`, watchID)
+}
+
+// PendingWatchVideos returns the videos still to be summarized, oldest first.
+func (p *PostgresDB) PendingWatchVideos(ctx context.Context, watchID string) ([]WatchVideo, error) {
+	return p.queryWatchVideos(ctx,
+		`v.watch_id = $1 AND v.status = $2 ORDER BY v.created_at`, watchID, ItemPending)
+}
+
+func (p *PostgresDB) UpdateWatchVideoStatus(ctx context.Context, id, status, errMsg string, contentID *string) error {
+	_, err := p.Conn.Exec(ctx,
+		`UPDATE channel_watch_videos
+		 SET status = $2, error = NULLIF($3, ''), content_id = COALESCE($4, content_id)
+		 WHERE id = $1`,
+		id, status, errMsg, contentID)
+	return err
+}
+
+// RequeueProcessingWatchVideos puts videos that were being processed when the
+// service stopped back in the queue.
+func (p *PostgresDB) RequeueProcessingWatchVideos(ctx context.Context) error {
+	_, err := p.Conn.Exec(ctx,
+		`UPDATE channel_watch_videos SET status = $1 WHERE status = $2`,
+		ItemPending, ItemProcessing)
+	return err
+}
+
+// Statuses of a resumable upload. Processing is tracked by its job once the
+// upload is complete.
+const (
+	UploadReceiving = "uploading"
+	UploadComplete  = "complete"
+	UploadFailed    = "error"
+)
+
+// ResumableUpload is a tus upload. Its bytes are stored as the parts of a
+// multipart upload of ObjectKey; Offset counts only the bytes already stored
+// as parts.
+type ResumableUpload struct {
+	ID          string            `json:"id"`
+	Filename    string            `json:"filename"`
+	ContentType string            `json:"content_type"`
+	Length      int64             `json:"length"`
+	Offset      int64             `json:"offset"`
+	ObjectKey   string            `json:"-"`
+	MultipartID string            `json:"-"`
+	PartETags   []string          `json:"-"`
+	Checksum    *string           `json:"checksum,omitempty"`
+	Metadata    map[string]string `json:"metadata"`
+	Status      string            `json:"status"`
+	JobID       *string           `json:"job_id,omitempty"`
+	Error       *string           `json:"error,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+const resumableUploadColumns = `id, filename, content_type, upload_length, upload_offset, object_key, multipart_id,
+	part_etags, checksum, metadata, status, job_id, error, created_at, updated_at`
+
+func scanResumableUpload(row pgx.Row, u *ResumableUpload) error {
+	return row.Scan(&u.ID, &u.Filename, &u.ContentType, &u.Length, &u.Offset, &u.ObjectKey, &u.MultipartID,
+		&u.PartETags, &u.Checksum, &u.Metadata, &u.Status, &u.JobID, &u.Error, &u.CreatedAt, &u.UpdatedAt)
+}
+
+func (p *PostgresDB) CreateResumableUpload(ctx context.Context, u ResumableUpload) (*ResumableUpload, error) {
+	var out ResumableUpload
+	err := scanResumableUpload(p.Conn.QueryRow(ctx, `
+		INSERT INTO resumable_uploads (id, filename, content_type, upload_length, object_key, multipart_id, checksum, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+resumableUploadColumns,
+		u.ID, u.Filename, u.ContentType, u.Length, u.ObjectKey, u.MultipartID, u.Checksum, u.Metadata), &out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+	return &out, nil
+}
+
+// GetResumableUpload returns the upload with the given ID, or nil if there is
+// none.
+func (p *PostgresDB) GetResumableUpload(ctx context.Context, id string) (*ResumableUpload, error) {
+	var u ResumableUpload
+	err := scanResumableUpload(p.Conn.QueryRow(ctx,
+		`SELECT `+resumableUploadColumns+` FROM resumable_uploads WHERE id = $1`, id), &u)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// AddResumableUploadPart records the next part of an upload and moves its
+// offset past it.
+func (p *PostgresDB) AddResumableUploadPart(ctx context.Context, id, etag string, size int64) error {
+	_, err := p.Conn.Exec(ctx,
+		`UPDATE resumable_uploads
+		 SET part_etags = array_append(part_etags, $2), upload_offset = upload_offset + $3, updated_at = NOW()
+		 WHERE id = $1`,
+		id, etag, size)
+	return err
+}
+
+func (p *PostgresDB) UpdateResumableUploadStatus(ctx context.Context, id, status, jobID, errMsg string) error {
+	_, err := p.Conn.Exec(ctx,
+		`UPDATE resumable_uploads
+		 SET status = $2, job_id = COALESCE(NULLIF($3, ''), job_id), error = NULLIF($4, ''), updated_at = NOW()
+		 WHERE id = $1`,
+		id, status, jobID, errMsg)
+	return err
+}
+
+// StaleResumableUploads lists the uploads still receiving bytes that have not
+// stored a part since before.
+func (p *PostgresDB) StaleResumableUploads(ctx context.Context, before time.Time) ([]ResumableUpload, error) {
+	rows, err := p.Conn.Query(ctx,
+		`SELECT `+resumableUploadColumns+` FROM resumable_uploads WHERE status = $1 AND updated_at < $2`,
+		UploadReceiving, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ResumableUpload
+	for rows.Next() {
+		var u ResumableUpload
+		if err := scanResumableUpload(rows, &u); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+func (p *PostgresDB) DeleteResumableUpload(ctx context.Context, id string) error {
+	_, err := p.Conn.Exec(ctx, `DELETE FROM resumable_uploads WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,46 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+)
+
+const MimeCSV = "text/csv"
+
+func init() {
+	Register(MimeCSV, ExtractorFunc(extractCSV))
+}
+
+func extractCSV(ctx context.Context, data []byte) (*Document, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = sniffDelimiter(data)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse csv: %w", err)
+	}
+
+	doc := &Document{}
+	doc.addTable(0, "", rows)
+	return doc, nil
+}
+
+// sniffDelimiter picks whichever of comma, semicolon and tab occurs most in
+// the first line; European exports often use semicolons.
+func sniffDelimiter(data []byte) rune {
+	first, _, _ := strings.Cut(string(data), "\n")
+	best, bestCount := ',', strings.Count(first, ",")
+	for _, d := range []rune{';', '\t'} {
+		if n := strings.Count(first, string(d)); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,112 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	MimePDF      = "application/pdf"
+	MimeText     = "text/plain"
+	MimeMarkdown = "text/markdown"
+	MimeHTML     = "text/html"
+	MimeRTF      = "application/rtf"
+	MimeDOCX     = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	MimeODT      = "application/vnd.oasis.opendocument.text"
+	MimeEPUB     = "application/epub+zip"
+)
+
+var extensionMimes = map[string]string{
+	".pdf":      MimePDF,
+	".txt":      MimeText,
+	".md":       MimeMarkdown,
+	".markdown": MimeMarkdown,
+	".html":     MimeHTML,
+	".htm":      MimeHTML,
+	".rtf":      MimeRTF,
+	".doc":      MimeDOC,
+	".docx":     MimeDOCX,
+	".odt":      MimeODT,
+	".epub":     MimeEPUB,
+	".pptx":     MimePPTX,
+	".odp":      MimeODP,
+	".png":      MimePNG,
+	".jpg":      MimeJPEG,
+	".jpeg":     MimeJPEG,
+	".tif":      MimeTIFF,
+	".tiff":     MimeTIFF,
+	".csv":      MimeCSV,
+	".xlsx":     MimeXLSX,
+}
+
+// DetectMIME sniffs the content first and only trusts the file extension to
+// tell apart formats that share a signature (zip containers, plain text
+// flavours). The returned type has no parameters.
+func DetectMIME(r io.ReaderAt, size int64, filename string) string {
+	head := make([]byte, 512)
+	n, _ := r.ReadAt(head, 0)
+	head = head[:n]
+
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return MimePDF
+	case bytes.HasPrefix(head, []byte(`{\rtf`)):
+		return MimeRTF
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		// http.DetectContentType does not know TIFF
+		return MimeTIFF
+	case bytes.HasPrefix(head, oleSignature) && ext == ".doc":
+		// the signature is shared with .xls and .ppt
+		return MimeDOC
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		if m := detectZip(r, size); m != "" {
+			return m
+		}
+		return "application/zip"
+	}
+
+	sniffed := http.DetectContentType(head)
+	if i := strings.Index(sniffed, ";"); i >= 0 {
+		sniffed = sniffed[:i]
+	}
+
+	if sniffed == MimeText || sniffed == MimeHTML {
+		if m, ok := extensionMimes[ext]; ok && strings.HasPrefix(m, "text/") {
+			return m
+		}
+	}
+	return sniffed
+}
+
+func detectZip(r io.ReaderAt, size int64) string {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return ""
+	}
+
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml":
+			return MimeDOCX
+		case "ppt/presentation.xml":
+			return MimePPTX
+		case "xl/workbook.xml":
+			return MimeXLSX
+		case "mimetype":
+			rc, err := f.Open()
+			if err != nil {
+				continue
+			}
+			b, _ := io.ReadAll(io.LimitReader(rc, 128))
+			rc.Close()
+			return strings.TrimSpace(string(b))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// MimeDOC is the legacy binary Word format, read with antiword.
+const MimeDOC = "application/msword"
+
+// ErrDOCUnavailable is returned for .doc files when antiword is not
+// installed.
+var ErrDOCUnavailable = errors.New("legacy .doc files need antiword installed; save the file as .docx instead")
+
+// oleSignature starts every OLE2 compound file: .doc, but also .xls and .ppt.
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+func init() {
+	Register(MimeDOC, ExtractorFunc(extractDOC))
+}
+
+// extractDOC converts a Word 97-2003 file to text with antiword. Its output
+// has no structure beyond paragraphs, which are separated by blank lines.
+func extractDOC(ctx context.Context, data []byte) (*Document, error) {
+	if _, err := exec.LookPath("antiword"); err != nil {
+		return nil, ErrDOCUnavailable
+	}
+
+	tmp, err := os.CreateTemp("", "doc-*.doc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := tmp.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write doc to temp file: %w", err)
+	}
+
+	// -w 0 keeps every paragraph on one line
+	cmd := exec.CommandContext(ctx, "antiword", "-w", "0", tmp.Name())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("antiword failed: %v: %s", err, stderr.String())
+	}
+
+	doc := &Document{}
+	for _, para := range splitParagraphs(string(out)) {
+		doc.add(Paragraph, 0, para)
+	}
+	return doc, nil
+}
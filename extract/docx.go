@@ -0,0 +1,160 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register(MimeDOCX, ExtractorFunc(extractDOCX))
+}
+
+func extractDOCX(ctx context.Context, data []byte) (*Document, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := readZipMember(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	// styles.xml maps custom style ids to their display names ("Heading 1")
+	styleNames := map[string]string{}
+	if styles, err := readZipMember(zr, "word/styles.xml"); err == nil {
+		styleNames = docxStyleNames(styles)
+	}
+
+	doc := &Document{}
+	dec := xml.NewDecoder(bytes.NewReader(body))
+
+	var (
+		text    strings.Builder
+		style   string
+		listLvl = -1
+		inPara  bool
+		inText  bool
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				inPara = true
+				text.Reset()
+				style = ""
+				listLvl = -1
+			case "pStyle":
+				style = attr(t, "val")
+				if name, ok := styleNames[style]; ok {
+					style = name
+				}
+			case "ilvl":
+				listLvl, _ = strconv.Atoi(attr(t, "val"))
+			case "numPr":
+				if listLvl < 0 {
+					listLvl = 0
+				}
+			case "t":
+				inText = true
+			case "tab":
+				if inPara {
+					text.WriteString(" ")
+				}
+			case "br", "cr":
+				if inPara {
+					text.WriteString(" ")
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				inPara = false
+				emitStyled(doc, style, listLvl, text.String())
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+
+	return doc, nil
+}
+
+func docxStyleNames(styles []byte) map[string]string {
+	names := map[string]string{}
+	dec := xml.NewDecoder(bytes.NewReader(styles))
+	var current string
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch se.Name.Local {
+		case "style":
+			current = attr(se, "styleId")
+		case "name":
+			if current != "" {
+				names[current] = attr(se, "val")
+			}
+		}
+	}
+	return names
+}
+
+// emitStyled maps word-processor paragraph style names onto blocks. Both
+// DOCX ("Heading 2", "heading2") and ODT ("Heading_20_2") spellings land here.
+func emitStyled(doc *Document, style string, listLvl int, text string) {
+	norm := strings.ToLower(strings.NewReplacer(" ", "", "_20_", "", "_", "").Replace(style))
+
+	switch {
+	case norm == "title":
+		if doc.Title == "" {
+			doc.Title = strings.TrimSpace(text)
+		}
+		doc.add(Heading, 1, text)
+	case strings.HasPrefix(norm, "heading"):
+		level, err := strconv.Atoi(strings.TrimPrefix(norm, "heading"))
+		if err != nil || level < 1 {
+			level = 1
+		}
+		doc.add(Heading, level, text)
+	case listLvl >= 0 || strings.HasPrefix(norm, "listparagraph") || strings.HasPrefix(norm, "listbullet") || strings.HasPrefix(norm, "listnumber"):
+		if listLvl < 0 {
+			listLvl = 0
+		}
+		doc.add(ListItem, listLvl, text)
+	default:
+		doc.add(Paragraph, 0, text)
+	}
+}
+
+func attr(se xml.StartElement, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
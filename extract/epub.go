@@ -0,0 +1,301 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxEPUBInflated caps what all members read from one book add up to, on top
+// of the per-member limit of readZipMember.
+const maxEPUBInflated = 256 << 20
+
+var errEPUBTooLarge = fmt.Errorf("epub expands to more than %d MB", maxEPUBInflated>>20)
+
+func init() {
+	Register(MimeEPUB, ExtractorFunc(extractEPUB))
+}
+
+type Chapter struct {
+	Title    string    `json:"title"`
+	Document *Document `json:"document"`
+}
+
+type Book struct {
+	Title    string    `json:"title"`
+	Author   string    `json:"author,omitempty"`
+	Chapters []Chapter `json:"chapters"`
+}
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Title    []string `xml:"metadata>title"`
+	Creator  []string `xml:"metadata>creator"`
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"`
+		ItemRefs []struct {
+			IDRef  string `xml:"idref,attr"`
+			Linear string `xml:"linear,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type ncxNavPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	Children []ncxNavPoint `xml:"navPoint"`
+}
+
+type ncxDoc struct {
+	NavPoints []ncxNavPoint `xml:"navMap>navPoint"`
+}
+
+// ParseEPUB reads the reading order from the OPF spine and names chapters
+// from the table of contents (EPUB 3 nav document or EPUB 2 NCX). Spine
+// documents that the TOC does not mention are folded into the preceding
+// chapter, which keeps split chapter files together.
+func ParseEPUB(data []byte) (*Book, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var inflated int
+	read := func(name string) ([]byte, error) {
+		b, err := readZipMember(zr, name)
+		if err != nil {
+			return nil, err
+		}
+		if inflated += len(b); inflated > maxEPUBInflated {
+			return nil, errEPUBTooLarge
+		}
+		return b, nil
+	}
+
+	containerXML, err := read("META-INF/container.xml")
+	if err != nil {
+		return nil, err
+	}
+	var container epubContainer
+	if err := xml.Unmarshal(containerXML, &container); err != nil {
+		return nil, fmt.Errorf("invalid container.xml: %w", err)
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, errors.New("epub has no rootfile")
+	}
+
+	opfPath := container.Rootfiles[0].FullPath
+	opfXML, err := read(opfPath)
+	if err != nil {
+		return nil, err
+	}
+	var pkg epubPackage
+	if err := xml.Unmarshal(opfXML, &pkg); err != nil {
+		return nil, fmt.Errorf("invalid package document: %w", err)
+	}
+
+	base := path.Dir(opfPath)
+	resolve := func(href string) string { return epubPath(base, href) }
+
+	hrefs := map[string]string{}
+	var navHref, ncxHref string
+	for _, item := range pkg.Manifest {
+		hrefs[item.ID] = resolve(item.Href)
+		if strings.Contains(item.Properties, "nav") {
+			navHref = resolve(item.Href)
+		}
+		if item.ID == pkg.Spine.Toc || item.MediaType == "application/x-dtbncx+xml" {
+			ncxHref = resolve(item.Href)
+		}
+	}
+
+	titles := map[string]string{}
+	if navHref != "" {
+		if b, err := read(navHref); err == nil {
+			epubNavTitles(b, path.Dir(navHref), titles)
+		}
+	}
+	if len(titles) == 0 && ncxHref != "" {
+		if b, err := read(ncxHref); err == nil {
+			var ncx ncxDoc
+			if xml.Unmarshal(b, &ncx) == nil {
+				ncxTitles(ncx.NavPoints, path.Dir(ncxHref), titles)
+			}
+		}
+	}
+
+	book := &Book{}
+	if len(pkg.Title) > 0 {
+		book.Title = strings.TrimSpace(pkg.Title[0])
+	}
+	if len(pkg.Creator) > 0 {
+		book.Author = strings.TrimSpace(pkg.Creator[0])
+	}
+
+	// a spine that lists a document twice would read it twice
+	seen := map[string]bool{}
+	for _, ref := range pkg.Spine.ItemRefs {
+		if ref.Linear == "no" {
+			continue
+		}
+		href, ok := hrefs[ref.IDRef]
+		if !ok || href == navHref || seen[href] {
+			continue
+		}
+		seen[href] = true
+		b, err := read(href)
+		if err != nil {
+			return nil, err
+		}
+		root, err := html.Parse(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", href, err)
+		}
+		doc := htmlDocument(root)
+
+		title, inTOC := titles[href]
+		if !inTOC && len(book.Chapters) > 0 {
+			last := &book.Chapters[len(book.Chapters)-1]
+			last.Document.Blocks = append(last.Document.Blocks, doc.Blocks...)
+			continue
+		}
+		if title == "" {
+			title = firstHeading(doc)
+		}
+		if title == "" {
+			title = fmt.Sprintf("Section %d", len(book.Chapters)+1)
+		}
+		book.Chapters = append(book.Chapters, Chapter{Title: title, Document: doc})
+	}
+
+	// drop covers, blank pages and other spine items with no text
+	chapters := book.Chapters[:0]
+	for _, ch := range book.Chapters {
+		if len(ch.Document.Blocks) > 0 {
+			chapters = append(chapters, ch)
+		}
+	}
+	book.Chapters = chapters
+
+	if len(book.Chapters) == 0 {
+		return nil, errors.New("epub contains no readable chapters")
+	}
+	return book, nil
+}
+
+// epubPath turns an href relative to base into the name of a zip member.
+// Hrefs are URLs, so "Chapter%201.xhtml" names the member "Chapter 1.xhtml";
+// the fragment is dropped.
+func epubPath(base, href string) string {
+	if i := strings.IndexByte(href, '#'); i >= 0 {
+		href = href[:i]
+	}
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Clean(path.Join(base, href))
+}
+
+func ncxTitles(points []ncxNavPoint, base string, titles map[string]string) {
+	for _, p := range points {
+		href := epubPath(base, p.Content.Src)
+		if _, seen := titles[href]; !seen {
+			titles[href] = strings.TrimSpace(p.Label)
+		}
+		ncxTitles(p.Children, base, titles)
+	}
+}
+
+func epubNavTitles(navXHTML []byte, base string, titles map[string]string) {
+	root, err := html.Parse(bytes.NewReader(navXHTML))
+	if err != nil {
+		return
+	}
+
+	var toc *html.Node
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if toc != nil {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "nav" {
+			for _, a := range n.Attr {
+				if a.Key == "epub:type" && strings.Contains(a.Val, "toc") {
+					toc = n
+					return
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(root)
+	if toc == nil {
+		return
+	}
+
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			for _, a := range n.Attr {
+				if a.Key != "href" {
+					continue
+				}
+				href := epubPath(base, a.Val)
+				if _, seen := titles[href]; !seen {
+					titles[href] = strings.Join(strings.Fields(nodeText(n)), " ")
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(toc)
+}
+
+func firstHeading(doc *Document) string {
+	for _, b := range doc.Blocks {
+		if b.Kind == Heading {
+			return b.Text
+		}
+	}
+	return ""
+}
+
+// extractEPUB flattens the whole book into one document, with each chapter
+// introduced by a level-1 heading.
+func extractEPUB(ctx context.Context, data []byte) (*Document, error) {
+	book, err := ParseEPUB(data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Title: book.Title}
+	for _, ch := range book.Chapters {
+		doc.add(Heading, 1, ch.Title)
+		doc.Blocks = append(doc.Blocks, ch.Document.Blocks...)
+	}
+	return doc, nil
+}
@@ -0,0 +1,145 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type BlockKind string
+
+const (
+	Heading   BlockKind = "heading"
+	Paragraph BlockKind = "paragraph"
+	ListItem  BlockKind = "list_item"
+)
+
+// Block is one structural unit of an extracted document. Level is the
+// heading level (1-6) for headings and the nesting depth (0-based) for list items.
+type Block struct {
+	Kind  BlockKind `json:"kind"`
+	Level int       `json:"level,omitempty"`
+	Text  string    `json:"text"`
+	// Page is the 1-based source page for formats that have pages.
+	Page  int        `json:"page,omitempty"`
+	Table *TableData `json:"table,omitempty"`
+}
+
+type Document struct {
+	Title  string  `json:"title,omitempty"`
+	Blocks []Block `json:"blocks"`
+	// PageCount is set by extractors whose format has pages or slides.
+	PageCount int `json:"page_count,omitempty"`
+	// OCRPages lists the pages whose text came from OCR rather than the file.
+	OCRPages []OCRPage `json:"ocr_pages,omitempty"`
+}
+
+func (d *Document) add(kind BlockKind, level int, text string) {
+	d.addOnPage(0, kind, level, text)
+}
+
+func (d *Document) addOnPage(page int, kind BlockKind, level int, text string) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return
+	}
+	d.Blocks = append(d.Blocks, Block{Kind: kind, Level: level, Text: text, Page: page})
+}
+
+// Text renders the document as lightweight markdown so the summarizer can see
+// headings ("#"), list items ("-") and tables without any extra prompt
+// plumbing. Page boundaries are marked with a "[Page N]" line.
+func (d *Document) Text() string {
+	var sb strings.Builder
+	page := 0
+	for _, b := range d.Blocks {
+		if b.Page > 0 && b.Page != page {
+			page = b.Page
+			fmt.Fprintf(&sb, "[Page %d]\n\n", page)
+		}
+		switch b.Kind {
+		case Heading:
+			level := b.Level
+			if level < 1 {
+				level = 1
+			}
+			if level > 6 {
+				level = 6
+			}
+			if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n\n") {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(strings.Repeat("#", level))
+			sb.WriteString(" ")
+			sb.WriteString(b.Text)
+			sb.WriteString("\n\n")
+		case ListItem:
+			sb.WriteString(strings.Repeat("  ", b.Level))
+			sb.WriteString("- ")
+			sb.WriteString(b.Text)
+			sb.WriteString("\n")
+		case Table:
+			if b.Table == nil {
+				continue
+			}
+			if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n\n") {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(b.Table.Markdown())
+			sb.WriteString("\n\n")
+		default:
+			sb.WriteString(b.Text)
+			sb.WriteString("\n\n")
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// HasStructure reports whether the document carries any heading or list hints.
+func (d *Document) HasStructure() bool {
+	for _, b := range d.Blocks {
+		if b.Kind != Paragraph {
+			return true
+		}
+	}
+	return false
+}
+
+type Extractor interface {
+	Extract(ctx context.Context, data []byte) (*Document, error)
+}
+
+type ExtractorFunc func(ctx context.Context, data []byte) (*Document, error)
+
+func (f ExtractorFunc) Extract(ctx context.Context, data []byte) (*Document, error) {
+	return f(ctx, data)
+}
+
+var ErrUnsupported = errors.New("unsupported document type")
+
+var registry = map[string]Extractor{}
+
+// Register makes an extractor available for a MIME type. Registering the
+// same type twice replaces the earlier extractor.
+func Register(mime string, e Extractor) {
+	registry[mime] = e
+}
+
+func For(mime string) (Extractor, bool) {
+	e, ok := registry[mime]
+	return e, ok
+}
+
+func Supported(mime string) bool {
+	_, ok := registry[mime]
+	return ok
+}
+
+func Extract(ctx context.Context, mime string, data []byte) (*Document, error) {
+	e, ok := For(mime)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, mime)
+	}
+	return e.Extract(ctx, data)
+}
@@ -0,0 +1,157 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func init() {
+	Register(MimeHTML, ExtractorFunc(extractHTML))
+}
+
+var skipAtoms = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+	atom.Head:     true,
+}
+
+var headingLevels = map[atom.Atom]int{
+	atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+}
+
+// blockAtoms end the current run of inline text.
+var blockAtoms = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true,
+	atom.Main: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Br: true, atom.Hr: true,
+	atom.Table: true, atom.Tr: true, atom.Td: true, atom.Th: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Figure: true, atom.Figcaption: true, atom.Nav: true,
+}
+
+func extractHTML(ctx context.Context, data []byte) (*Document, error) {
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+	return htmlDocument(root), nil
+}
+
+func htmlDocument(root *html.Node) *Document {
+	w := &htmlWalker{doc: &Document{}}
+	if t := findFirst(root, atom.Title); t != nil {
+		w.doc.Title = strings.TrimSpace(nodeText(t))
+	}
+	w.walk(root)
+	w.flush()
+	return w.doc
+}
+
+type htmlWalker struct {
+	doc       *Document
+	buf       strings.Builder
+	listDepth int
+}
+
+func (w *htmlWalker) flush() {
+	w.doc.add(Paragraph, 0, w.buf.String())
+	w.buf.Reset()
+}
+
+func (w *htmlWalker) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.buf.WriteString(n.Data)
+		return
+	case html.ElementNode:
+		if skipAtoms[n.DataAtom] {
+			return
+		}
+		if level, ok := headingLevels[n.DataAtom]; ok {
+			w.flush()
+			w.doc.add(Heading, level, nodeText(n))
+			return
+		}
+		if n.DataAtom == atom.Li {
+			w.flush()
+			w.listItem(n)
+			return
+		}
+		if blockAtoms[n.DataAtom] {
+			w.flush()
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+
+	if n.Type == html.ElementNode && blockAtoms[n.DataAtom] {
+		w.flush()
+	}
+}
+
+// listItem emits the item's own text and then recurses into nested lists
+// one level deeper.
+func (w *htmlWalker) listItem(n *html.Node) {
+	var own strings.Builder
+	var nested []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (c.DataAtom == atom.Ul || c.DataAtom == atom.Ol) {
+			nested = append(nested, c)
+			continue
+		}
+		own.WriteString(nodeText(c))
+		own.WriteString(" ")
+	}
+	w.doc.add(ListItem, w.listDepth, own.String())
+
+	w.listDepth++
+	for _, l := range nested {
+		w.walk(l)
+	}
+	w.listDepth--
+}
+
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var rec func(*html.Node)
+	rec = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			return
+		}
+		if n.Type == html.ElementNode && skipAtoms[n.DataAtom] {
+			return
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Br {
+			sb.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			rec(c)
+		}
+	}
+	rec(n)
+	return sb.String()
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if f := findFirst(c, a); f != nil {
+			return f
+		}
+	}
+	return nil
+}
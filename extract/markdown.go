@@ -0,0 +1,91 @@
+package extract
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	Register(MimeMarkdown, ExtractorFunc(extractMarkdown))
+}
+
+var (
+	mdHeading   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdListItem  = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])\s+(.*)$`)
+	mdSetextH1  = regexp.MustCompile(`^=+\s*$`)
+	mdSetextH2  = regexp.MustCompile(`^-+\s*$`)
+	mdFence     = regexp.MustCompile("^\\s*(```|~~~)")
+	mdInlineTag = regexp.MustCompile("[*_`]+")
+	mdLink      = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+)
+
+func extractMarkdown(ctx context.Context, data []byte) (*Document, error) {
+	doc := &Document{}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			doc.add(Paragraph, 0, cleanInline(strings.Join(para, " ")))
+			para = nil
+		}
+	}
+
+	inFence := false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if mdFence.MatchString(line) {
+			flush()
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			para = append(para, line)
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			flush()
+			doc.add(Heading, len(m[1]), cleanInline(m[2]))
+			continue
+		}
+
+		// setext headings: a paragraph line underlined with === or ---
+		if len(para) == 0 && i+1 < len(lines) && strings.TrimSpace(line) != "" {
+			if mdSetextH1.MatchString(lines[i+1]) {
+				doc.add(Heading, 1, cleanInline(line))
+				i++
+				continue
+			}
+			if mdSetextH2.MatchString(lines[i+1]) && !mdListItem.MatchString(line) {
+				doc.add(Heading, 2, cleanInline(line))
+				i++
+				continue
+			}
+		}
+
+		if m := mdListItem.FindStringSubmatch(line); m != nil {
+			flush()
+			indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+			doc.add(ListItem, indent/2, cleanInline(m[2]))
+			continue
+		}
+
+		para = append(para, strings.TrimPrefix(strings.TrimSpace(line), "> "))
+	}
+	flush()
+
+	return doc, nil
+}
+
+func cleanInline(s string) string {
+	s = mdLink.ReplaceAllString(s, "$1")
+	return mdInlineTag.ReplaceAllString(s, "")
+}
@@ -0,0 +1,170 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	MimePNG  = "image/png"
+	MimeJPEG = "image/jpeg"
+	MimeTIFF = "image/tiff"
+)
+
+// OCRLanguage is passed to tesseract's -l flag, e.g. "eng" or "eng+fra".
+var OCRLanguage = "eng"
+
+// OCRMinConfidence is the mean word confidence (0-100) below which an OCR'd
+// page is flagged as low quality.
+var OCRMinConfidence = 60.0
+
+// ErrOCRUnavailable is returned when tesseract, or pdftoppm for PDF pages,
+// is not installed.
+var ErrOCRUnavailable = errors.New("ocr is not available")
+
+type OCRPage struct {
+	Page       int     `json:"page"`
+	Confidence float64 `json:"confidence"`
+	LowQuality bool    `json:"low_quality"`
+}
+
+func init() {
+	Register(MimePNG, ExtractorFunc(extractImage(".png")))
+	Register(MimeJPEG, ExtractorFunc(extractImage(".jpg")))
+	Register(MimeTIFF, ExtractorFunc(extractImage(".tiff")))
+}
+
+func extractImage(ext string) func(context.Context, []byte) (*Document, error) {
+	return func(ctx context.Context, data []byte) (*Document, error) {
+		tmp, err := os.CreateTemp("", "ocr-*"+ext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp file: %w", err)
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		if _, err := tmp.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to write image to temp file: %w", err)
+		}
+
+		text, conf, err := OCRImage(ctx, tmp.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		doc := &Document{PageCount: 1}
+		doc.OCRPages = append(doc.OCRPages, newOCRPage(1, conf))
+		for _, para := range splitParagraphs(text) {
+			doc.addOnPage(1, Paragraph, 0, para)
+		}
+		return doc, nil
+	}
+}
+
+func newOCRPage(page int, conf float64) OCRPage {
+	return OCRPage{Page: page, Confidence: conf, LowQuality: conf < OCRMinConfidence}
+}
+
+// OCRImage runs tesseract on an image file and returns the recognised text
+// together with the mean confidence of its words.
+func OCRImage(ctx context.Context, path string) (string, float64, error) {
+	if _, err := exec.LookPath("tesseract"); err != nil {
+		return "", 0, fmt.Errorf("%w: tesseract is not installed", ErrOCRUnavailable)
+	}
+
+	cmd := exec.CommandContext(ctx, "tesseract", path, "stdout", "-l", OCRLanguage, "tsv")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", 0, fmt.Errorf("tesseract failed: %v: %s", err, stderr.String())
+	}
+
+	return parseTesseractTSV(out)
+}
+
+// parseTesseractTSV rebuilds lines from tesseract's word rows (level 5) and
+// averages their confidence; rows for blocks, paragraphs and lines carry -1.
+func parseTesseractTSV(out []byte) (string, float64, error) {
+	var (
+		sb       strings.Builder
+		lastLine string
+		lastPara string
+		confSum  float64
+		words    int
+	)
+
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	first := true
+	for sc.Scan() {
+		if first {
+			first = false
+			continue
+		}
+		cols := strings.Split(sc.Text(), "\t")
+		if len(cols) < 12 || cols[0] != "5" {
+			continue
+		}
+		word := strings.TrimSpace(cols[11])
+		conf, err := strconv.ParseFloat(cols[10], 64)
+		if err != nil || conf < 0 || word == "" {
+			continue
+		}
+
+		para := cols[2] + "." + cols[3]
+		line := para + "." + cols[4]
+		switch {
+		case sb.Len() == 0:
+		case para != lastPara:
+			sb.WriteString("\n\n")
+		case line != lastLine:
+			sb.WriteString("\n")
+		default:
+			sb.WriteString(" ")
+		}
+		sb.WriteString(word)
+		lastPara, lastLine = para, line
+
+		confSum += conf
+		words++
+	}
+	if err := sc.Err(); err != nil {
+		return "", 0, err
+	}
+
+	if words == 0 {
+		return "", 0, nil
+	}
+	return sb.String(), confSum / float64(words), nil
+}
+
+// ocrPDFPage renders one page with pdftoppm and OCRs the result.
+func ocrPDFPage(ctx context.Context, pdfPath string, page int) (string, float64, error) {
+	if _, err := exec.LookPath("pdftoppm"); err != nil {
+		return "", 0, fmt.Errorf("%w: pdftoppm is not installed", ErrOCRUnavailable)
+	}
+
+	dir, err := os.MkdirTemp("", "ocr-page-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.RemoveAll(dir)
+
+	prefix := filepath.Join(dir, "page")
+	n := strconv.Itoa(page)
+	cmd := exec.CommandContext(ctx, "pdftoppm", "-r", "300", "-f", n, "-l", n, "-png", "-singlefile", pdfPath, prefix)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", 0, fmt.Errorf("pdftoppm failed: %v: %s", err, out)
+	}
+
+	return OCRImage(ctx, prefix+".png")
+}
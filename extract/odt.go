@@ -0,0 +1,114 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// odfMaxSpaces caps the run a single text:s element expands to; the count
+// comes from the file and long runs mean nothing to the summarizer.
+const odfMaxSpaces = 64
+
+func init() {
+	Register(MimeODT, ExtractorFunc(extractODT))
+}
+
+func extractODT(ctx context.Context, data []byte) (*Document, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := readZipMember(zr, "content.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	if err := walkODFText(content, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// walkODFText reads the text:* vocabulary shared by ODT, ODP and friends.
+// Paragraphs nested inside another paragraph-level element (frames, notes)
+// are flattened into their own blocks.
+func walkODFText(content []byte, doc *Document) error {
+	dec := xml.NewDecoder(bytes.NewReader(content))
+
+	type para struct {
+		text    strings.Builder
+		heading int
+		style   string
+	}
+
+	var (
+		stack     []*para
+		listDepth = -1
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "list":
+				listDepth++
+			case "h":
+				level, _ := strconv.Atoi(attr(t, "outline-level"))
+				if level < 1 {
+					level = 1
+				}
+				stack = append(stack, &para{heading: level})
+			case "p":
+				stack = append(stack, &para{style: attr(t, "style-name")})
+			case "s":
+				if len(stack) > 0 {
+					n, _ := strconv.Atoi(attr(t, "c"))
+					n = min(max(n, 1), odfMaxSpaces)
+					stack[len(stack)-1].text.WriteString(strings.Repeat(" ", n))
+				}
+			case "tab", "line-break":
+				if len(stack) > 0 {
+					stack[len(stack)-1].text.WriteString(" ")
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "list":
+				listDepth--
+			case "h", "p":
+				if len(stack) == 0 {
+					continue
+				}
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+
+				switch {
+				case p.heading > 0:
+					doc.add(Heading, p.heading, p.text.String())
+				case listDepth >= 0:
+					doc.add(ListItem, listDepth, p.text.String())
+				default:
+					emitStyled(doc, p.style, -1, p.text.String())
+				}
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+}
@@ -0,0 +1,624 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+const (
+	PDFBackendNative    = "native"
+	PDFBackendPdftotext = "pdftotext"
+)
+
+// PDFBackend picks the preferred extractor. The other backend is still tried
+// when the preferred one fails or finds no text at all.
+var PDFBackend = PDFBackendNative
+
+// PDFTimeout bounds a single PDF extraction, whichever backend runs it and
+// including the OCR of scanned pages.
+var PDFTimeout = 60 * time.Second
+
+var ErrPDFTimeout = errors.New("pdf text extraction timed out")
+
+var (
+	ErrPDFPasswordRequired = errors.New("pdf_password_required")
+	ErrPDFPasswordInvalid  = errors.New("pdf_password_invalid")
+)
+
+// errPDFDecrypt means an encrypted PDF opened but could not be written out
+// decrypted, which unipdf only does with a license. The poppler tools are
+// skipped for it rather than given the password on their command line.
+var errPDFDecrypt = errors.New("could not write a decrypted copy of the pdf")
+
+type pdfPasswordKey struct{}
+
+// WithPDFPassword attaches the password of an encrypted PDF to ctx so the
+// extractors can decrypt it. It only lives as long as the request.
+func WithPDFPassword(ctx context.Context, password string) context.Context {
+	return context.WithValue(ctx, pdfPasswordKey{}, password)
+}
+
+func pdfPassword(ctx context.Context) string {
+	pw, _ := ctx.Value(pdfPasswordKey{}).(string)
+	return pw
+}
+
+// popplerTempFile writes data to a temporary file for the poppler tools and
+// returns its path; the caller removes it. An encrypted PDF is decrypted here
+// with the password from ctx, so the password never reaches a command line
+// where other processes could read it.
+func popplerTempFile(ctx context.Context, data []byte) (string, error) {
+	tmpFile, err := os.CreateTemp("", "pdf-*.pdf")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer tmpFile.Close()
+
+	if err := writePopplerPDF(tmpFile, data, pdfPassword(ctx)); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	return tmpFile.Name(), nil
+}
+
+func writePopplerPDF(w io.Writer, data []byte, password string) error {
+	if password == "" {
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to write PDF to temp file: %w", err)
+		}
+		return nil
+	}
+
+	reader, err := OpenPDF(bytes.NewReader(data), password)
+	if err != nil {
+		return err
+	}
+	writer, err := reader.ToWriter(nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPDFDecrypt, err)
+	}
+	if err := writer.Write(w); err != nil {
+		return fmt.Errorf("%w: %v", errPDFDecrypt, err)
+	}
+	return nil
+}
+
+// OpenPDF opens a PDF and decrypts it when needed. Files that are only
+// restricted by an owner password open without one.
+func OpenPDF(r io.ReadSeeker, password string) (*model.PdfReader, error) {
+	reader, err := model.NewPdfReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pdf: %w", err)
+	}
+
+	encrypted, err := reader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if !encrypted {
+		return reader, nil
+	}
+
+	if ok, err := reader.Decrypt([]byte("")); err == nil && ok {
+		return reader, nil
+	}
+	if password == "" {
+		return nil, ErrPDFPasswordRequired
+	}
+	if ok, err := reader.Decrypt([]byte(password)); err != nil || !ok {
+		return nil, ErrPDFPasswordInvalid
+	}
+	return reader, nil
+}
+
+func isPasswordErr(err error) bool {
+	return errors.Is(err, ErrPDFPasswordRequired) || errors.Is(err, ErrPDFPasswordInvalid)
+}
+
+type Page struct {
+	Number    int    `json:"number"`
+	Text      string `json:"text"`
+	HasImages bool   `json:"has_images,omitempty"`
+
+	// imagesKnown is false for backends that cannot see images (pdftotext).
+	imagesKnown bool
+}
+
+func init() {
+	Register(MimePDF, ExtractorFunc(extractPDF))
+}
+
+func extractPDF(ctx context.Context, data []byte) (*Document, error) {
+	ctx, cancel := context.WithTimeout(ctx, PDFTimeout)
+	defer cancel()
+
+	pages, err := PDFPages(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{PageCount: len(pages)}
+	if err := ocrImagePages(ctx, data, pages, doc); err != nil {
+		return nil, err
+	}
+	for _, p := range pages {
+		doc.addTextWithTables(p.Number, p.Text)
+	}
+	return doc, nil
+}
+
+// ocrImagePages fills in the text of image-only pages, i.e. scans. A page
+// without text is treated as image-only when the backend saw an image on it,
+// or whenever the backend cannot tell.
+func ocrImagePages(ctx context.Context, data []byte, pages []Page, doc *Document) error {
+	var todo []int
+	for i, p := range pages {
+		if strings.TrimSpace(p.Text) != "" {
+			continue
+		}
+		if p.HasImages || !p.imagesKnown {
+			todo = append(todo, i)
+		}
+	}
+	if len(todo) == 0 {
+		return nil
+	}
+
+	path, err := popplerTempFile(ctx, data)
+	if errors.Is(err, errPDFDecrypt) {
+		log.Printf("skipping ocr of %d image-only pdf pages: %v", len(todo), err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	for _, i := range todo {
+		text, conf, err := ocrPDFPage(ctx, path, pages[i].Number)
+		if errors.Is(err, ErrOCRUnavailable) {
+			log.Printf("skipping ocr of %d image-only pdf pages: %v", len(todo), err)
+			return nil
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return ErrPDFTimeout
+		}
+		if err != nil {
+			return fmt.Errorf("failed to ocr page %d: %w", pages[i].Number, err)
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		pages[i].Text = text
+		doc.OCRPages = append(doc.OCRPages, newOCRPage(pages[i].Number, conf))
+	}
+	return nil
+}
+
+// PDFPages returns the text of every page, in page order.
+func PDFPages(ctx context.Context, data []byte) ([]Page, error) {
+	ctx, cancel := context.WithTimeout(ctx, PDFTimeout)
+	defer cancel()
+
+	backends := []string{PDFBackendNative, PDFBackendPdftotext}
+	if PDFBackend == PDFBackendPdftotext {
+		backends[0], backends[1] = backends[1], backends[0]
+	}
+
+	var firstErr error
+	var empty []Page
+	for _, backend := range backends {
+		var pages []Page
+		var err error
+		switch backend {
+		case PDFBackendNative:
+			pages, err = nativePDFPages(ctx, data)
+		case PDFBackendPdftotext:
+			if _, lookErr := exec.LookPath("pdftotext"); lookErr != nil {
+				continue
+			}
+			pages, err = pdftotextPages(ctx, data)
+		}
+
+		if errors.Is(err, ErrPDFTimeout) || isPasswordErr(err) {
+			return nil, err
+		}
+		if err != nil {
+			log.Printf("pdf backend %s failed: %v", backend, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if hasText(pages) {
+			return pages, nil
+		}
+		if empty == nil {
+			empty = pages
+		}
+	}
+
+	// every backend ran but found no text, e.g. a scanned document
+	if empty != nil {
+		return empty, nil
+	}
+	if firstErr == nil {
+		firstErr = errors.New("no pdf text backend available")
+	}
+	return nil, firstErr
+}
+
+func hasText(pages []Page) bool {
+	for _, p := range pages {
+		if strings.TrimSpace(p.Text) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func runWithTimeout[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	type result struct {
+		v   T
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		v, err := fn()
+		ch <- result{v, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.v, r.err
+	case <-ctx.Done():
+		var zero T
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return zero, ErrPDFTimeout
+		}
+		return zero, ctx.Err()
+	}
+}
+
+func pdftotextPages(ctx context.Context, data []byte) ([]Page, error) {
+	path, err := popplerTempFile(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+
+	cmd := exec.CommandContext(ctx, "pdftotext", path, "-")
+	out, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, ErrPDFTimeout
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract text: %w", err)
+	}
+
+	// pdftotext ends every page with a form feed
+	raw := strings.Split(string(out), "\f")
+	if len(raw) > 0 && strings.TrimSpace(raw[len(raw)-1]) == "" {
+		raw = raw[:len(raw)-1]
+	}
+
+	pages := make([]Page, len(raw))
+	for i, text := range raw {
+		pages[i] = Page{Number: i + 1, Text: text}
+	}
+	return pages, nil
+}
+
+func nativePDFPages(ctx context.Context, data []byte) ([]Page, error) {
+	return runWithTimeout(ctx, func() ([]Page, error) {
+		reader, err := OpenPDF(bytes.NewReader(data), pdfPassword(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := reader.GetNumPages()
+		if err != nil {
+			return nil, err
+		}
+
+		pages := make([]Page, 0, n)
+		for i := 1; i <= n; i++ {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			page, err := reader.GetPage(i)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read page %d: %w", i, err)
+			}
+
+			contents, err := page.GetAllContentStreams()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read page %d content: %w", i, err)
+			}
+
+			w := &pdfTextWriter{ctx: ctx, fonts: map[string]*model.PdfFont{}}
+			w.run(contents, page.Resources, 0)
+			pages = append(pages, Page{Number: i, Text: w.String(), HasImages: w.images > 0, imagesKnown: true})
+		}
+		return pages, nil
+	})
+}
+
+// pdfTextWriter is a minimal content stream interpreter: it follows the
+// text-showing and text-positioning operators closely enough to put line
+// breaks and word gaps in the right places. Graphics are skipped, but images
+// are counted so scanned pages can be told apart from blank ones.
+type pdfTextWriter struct {
+	// ctx ends the interpretation early; runWithTimeout stops waiting at the
+	// deadline, and this makes the goroutine doing the work stop as well.
+	ctx context.Context
+	sb  strings.Builder
+	// last is the last byte written. A word space is only written once the
+	// next text arrives, so a tab or line break can still replace it.
+	last         byte
+	pendingSpace bool
+	fonts        map[string]*model.PdfFont
+	font         *model.PdfFont
+	fontSize     float64
+	leading      float64
+	lineY        float64
+	lineX        float64
+	started      bool
+	images       int
+	// curX estimates where the last shown text ended, so a jump well past it
+	// on the same baseline can be told apart from a word gap. curY is that
+	// text's baseline; unlike lineY it survives BT.
+	curX float64
+	curY float64
+}
+
+func (w *pdfTextWriter) String() string {
+	if w.pendingSpace {
+		return w.sb.String() + " "
+	}
+	return w.sb.String()
+}
+
+// write appends shown text, after the word space waiting in front of it.
+func (w *pdfTextWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	if w.pendingSpace {
+		w.sb.WriteByte(' ')
+		w.pendingSpace = false
+	}
+	w.sb.WriteString(s)
+	w.last = s[len(s)-1]
+}
+
+func (w *pdfTextWriter) newline() {
+	w.pendingSpace = false
+	if w.sb.Len() > 0 && w.last != '\n' {
+		w.sb.WriteByte('\n')
+		w.last = '\n'
+	}
+}
+
+func (w *pdfTextWriter) space() {
+	if w.sb.Len() > 0 && w.last != ' ' && w.last != '\n' {
+		w.pendingSpace = true
+	}
+}
+
+// moveTo handles an absolute line position; a change of baseline starts a new
+// line and a jump along the same baseline becomes a word gap.
+func (w *pdfTextWriter) moveTo(x, y float64) {
+	threshold := w.fontSize * 0.5
+	if threshold <= 0 {
+		threshold = 1
+	}
+	if w.started && abs(y-w.curY) > threshold {
+		w.newline()
+	} else if w.started && x-w.curX > w.columnGap() {
+		w.tab()
+	} else if w.started && x != w.curX {
+		w.space()
+	}
+	w.lineX, w.lineY = x, y
+	w.curX, w.curY = x, y
+	w.started = true
+}
+
+// columnGap is the horizontal jump that separates table cells rather than
+// words: wider than any word space, narrower than a typical column gutter.
+func (w *pdfTextWriter) columnGap() float64 {
+	if w.fontSize <= 0 {
+		return 12
+	}
+	return w.fontSize * 1.2
+}
+
+// tab marks a cell boundary; it replaces a pending space.
+func (w *pdfTextWriter) tab() {
+	w.pendingSpace = false
+	if w.sb.Len() == 0 || w.last == '\n' || w.last == '\t' {
+		return
+	}
+	w.sb.WriteByte('\t')
+	w.last = '\t'
+}
+
+// advance moves curX past the glyphs of b, using the font's widths where it
+// has them and half an em otherwise.
+func (w *pdfTextWriter) advance(b []byte) {
+	if w.font == nil {
+		w.curX += float64(len(b)) * w.fontSize * 0.5
+		return
+	}
+	for _, code := range w.font.BytesToCharcodes(b) {
+		wx := 500.0
+		if m, ok := w.font.GetCharMetrics(code); ok && m.Wx > 0 {
+			wx = m.Wx
+		}
+		w.curX += wx / 1000 * w.fontSize
+	}
+}
+
+func (w *pdfTextWriter) show(b []byte) {
+	w.advance(b)
+	if w.font != nil {
+		if s, _, _ := w.font.CharcodeBytesToUnicode(b); s != "" {
+			w.write(s)
+			return
+		}
+	}
+	w.write(string(bytes.Map(func(r rune) rune {
+		if r < 0x20 {
+			return -1
+		}
+		return r
+	}, b)))
+}
+
+func (w *pdfTextWriter) run(contents string, res *model.PdfPageResources, depth int) {
+	ops, err := contentstream.NewContentStreamParser(contents).Parse()
+	if err != nil {
+		return
+	}
+
+	for _, op := range *ops {
+		if w.ctx.Err() != nil {
+			return
+		}
+		nums := func() []float64 {
+			f, _ := core.GetNumbersAsFloat(op.Params)
+			return f
+		}
+
+		switch op.Operand {
+		case "BT":
+			w.lineX, w.lineY = 0, 0
+		case "Tf":
+			if len(op.Params) == 2 {
+				name, _ := core.GetNameVal(op.Params[0])
+				w.font = w.loadFont(res, name)
+				w.fontSize, _ = core.GetNumberAsFloat(op.Params[1])
+			}
+		case "TL":
+			if f := nums(); len(f) == 1 {
+				w.leading = f[0]
+			}
+		case "Td", "TD":
+			if f := nums(); len(f) == 2 {
+				if op.Operand == "TD" {
+					w.leading = -f[1]
+				}
+				w.moveTo(w.lineX+f[0], w.lineY+f[1])
+			}
+		case "Tm":
+			if f := nums(); len(f) == 6 {
+				w.moveTo(f[4], f[5])
+			}
+		case "T*":
+			w.moveTo(w.lineX, w.lineY-w.leading)
+			w.newline()
+		case "Tj":
+			if len(op.Params) == 1 {
+				if b, ok := core.GetStringBytes(op.Params[0]); ok {
+					w.show(b)
+				}
+			}
+		case "'", "\"":
+			w.moveTo(w.lineX, w.lineY-w.leading)
+			w.newline()
+			if len(op.Params) > 0 {
+				if b, ok := core.GetStringBytes(op.Params[len(op.Params)-1]); ok {
+					w.show(b)
+				}
+			}
+		case "TJ":
+			if len(op.Params) != 1 {
+				continue
+			}
+			arr, ok := core.GetArray(op.Params[0])
+			if !ok {
+				continue
+			}
+			for _, el := range arr.Elements() {
+				if b, ok := core.GetStringBytes(el); ok {
+					w.show(b)
+					continue
+				}
+				// kerning is in thousandths of an em; a big negative gap is a word break
+				if f, err := core.GetNumberAsFloat(el); err == nil {
+					w.curX -= f / 1000 * w.fontSize
+					if f < -250 {
+						w.space()
+					}
+				}
+			}
+		case "BI":
+			w.images++
+		case "Do":
+			if depth > 8 || res == nil || len(op.Params) != 1 {
+				continue
+			}
+			name, ok := core.GetName(op.Params[0])
+			if !ok {
+				continue
+			}
+			_, xtype := res.GetXObjectByName(*name)
+			if xtype == model.XObjectTypeImage {
+				w.images++
+				continue
+			}
+			if xtype != model.XObjectTypeForm {
+				continue
+			}
+			form, err := res.GetXObjectFormByName(*name)
+			if err != nil || form == nil {
+				continue
+			}
+			body, err := form.GetContentStream()
+			if err != nil {
+				continue
+			}
+			formRes := form.Resources
+			if formRes == nil {
+				formRes = res
+			}
+			w.newline()
+			w.run(string(body), formRes, depth+1)
+			w.newline()
+		}
+	}
+}
+
+func (w *pdfTextWriter) loadFont(res *model.PdfPageResources, name string) *model.PdfFont {
+	if f, ok := w.fonts[name]; ok {
+		return f
+	}
+	var font *model.PdfFont
+	if res != nil {
+		if obj, ok := res.GetFontByName(core.PdfObjectName(name)); ok {
+			font, _ = model.NewPdfFontFromPdfObject(obj)
+		}
+	}
+	w.fonts[name] = font
+	return font
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,229 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	unlikelyRe = regexp.MustCompile(`(?i)comment|footer|footnote|nav|sidebar|menu|share|social|related|promo|sponsor|advert|\bads?\b|cookie|banner|popup|modal|subscribe|newsletter|breadcrumb|masthead|widget`)
+	likelyRe   = regexp.MustCompile(`(?i)article|body|content|main|post|entry|text|story|blog`)
+)
+
+// boilerplateAtoms never hold the main content of a page.
+var boilerplateAtoms = map[atom.Atom]bool{
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Form: true, atom.Button: true, atom.Select: true, atom.Dialog: true,
+}
+
+// minArticleText is how much text the picked content must have before we
+// trust it over the whole page.
+const minArticleText = 200
+
+// ExtractArticle pulls the main content out of a web page, readability style:
+// boilerplate is dropped, paragraphs vote for their parent containers by
+// length and comma count, link-heavy containers are penalised, and the best
+// container plus its strong siblings is rendered. Pages where nothing stands
+// out fall back to the whole body.
+func ExtractArticle(data []byte) (*Document, error) {
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	title := pageTitle(root)
+	stripBoilerplate(root)
+
+	scores := map[*html.Node]float64{}
+	var score func(*html.Node)
+	score = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			score(c)
+		}
+		if n.Type != html.ElementNode {
+			return
+		}
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		default:
+			return
+		}
+		text := strings.Join(strings.Fields(nodeText(n)), " ")
+		if len(text) < 25 {
+			return
+		}
+		s := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		if p := n.Parent; p != nil && p.Type == html.ElementNode {
+			if _, ok := scores[p]; !ok {
+				scores[p] = baseScore(p)
+			}
+			scores[p] += s
+			if g := p.Parent; g != nil && g.Type == html.ElementNode {
+				if _, ok := scores[g]; !ok {
+					scores[g] = baseScore(g)
+				}
+				scores[g] += s / 2
+			}
+		}
+	}
+	score(root)
+
+	var best *html.Node
+	bestScore := 0.0
+	for n, s := range scores {
+		s *= 1 - linkDensity(n)
+		scores[n] = s
+		if s > bestScore {
+			best, bestScore = n, s
+		}
+	}
+
+	doc := &Document{Title: title}
+	if best != nil {
+		w := &htmlWalker{doc: doc}
+		for _, n := range articleNodes(best, bestScore, scores) {
+			w.walk(n)
+			w.flush()
+		}
+	}
+	if len(doc.Text()) < minArticleText {
+		doc = htmlDocument(root)
+		doc.Title = title
+	}
+	return doc, nil
+}
+
+// articleNodes returns the top candidate together with siblings that scored
+// close to it, which catches articles split over several containers.
+func articleNodes(best *html.Node, bestScore float64, scores map[*html.Node]float64) []*html.Node {
+	if best.Parent == nil {
+		return []*html.Node{best}
+	}
+	threshold := max(10, bestScore*0.2)
+
+	var nodes []*html.Node
+	for c := best.Parent.FirstChild; c != nil; c = c.NextSibling {
+		if c == best || scores[c] >= threshold {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func baseScore(n *html.Node) float64 {
+	s := 0.0
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		s += 10
+	case atom.Div:
+		s += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		s += 3
+	case atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		s -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		s -= 5
+	}
+	return s + classWeight(n)
+}
+
+func classWeight(n *html.Node) float64 {
+	hint := attrVal(n, "class") + " " + attrVal(n, "id")
+	w := 0.0
+	if unlikelyRe.MatchString(hint) {
+		w -= 25
+	}
+	if likelyRe.MatchString(hint) {
+		w += 25
+	}
+	return w
+}
+
+func linkDensity(n *html.Node) float64 {
+	total := len(strings.TrimSpace(nodeText(n)))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	var rec func(*html.Node)
+	rec = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			links += len(strings.TrimSpace(nodeText(n)))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			rec(c)
+		}
+	}
+	rec(n)
+	return min(float64(links)/float64(total), 1)
+}
+
+// stripBoilerplate detaches navigation, forms and containers whose class or
+// id marks them as page furniture, unless they also look like content.
+func stripBoilerplate(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && isBoilerplate(c) {
+			n.RemoveChild(c)
+		} else {
+			stripBoilerplate(c)
+		}
+		c = next
+	}
+}
+
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateAtoms[n.DataAtom] {
+		return true
+	}
+	if n.DataAtom == atom.Body || n.DataAtom == atom.Html || n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		return false
+	}
+	if attrVal(n, "aria-hidden") == "true" || attrVal(n, "role") == "navigation" {
+		return true
+	}
+	hint := attrVal(n, "class") + " " + attrVal(n, "id")
+	return unlikelyRe.MatchString(hint) && !likelyRe.MatchString(hint)
+}
+
+// pageTitle prefers og:title, which sites keep free of the " | Site name"
+// suffix they put in <title>.
+func pageTitle(root *html.Node) string {
+	var og string
+	var rec func(*html.Node)
+	rec = func(n *html.Node) {
+		if og != "" {
+			return
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Meta && attrVal(n, "property") == "og:title" {
+			og = strings.TrimSpace(attrVal(n, "content"))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			rec(c)
+		}
+	}
+	rec(root)
+	if og != "" {
+		return og
+	}
+	if t := findFirst(root, atom.Title); t != nil {
+		return strings.TrimSpace(nodeText(t))
+	}
+	return ""
+}
+
+func attrVal(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
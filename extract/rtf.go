@@ -0,0 +1,190 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func init() {
+	Register(MimeRTF, ExtractorFunc(extractRTF))
+}
+
+// rtfSkipDestinations are groups whose text is never part of the body.
+var rtfSkipDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true,
+	"pict": true, "object": true, "header": true, "footer": true,
+	"headerl": true, "headerr": true, "footerl": true, "footerr": true,
+	"listtable": true, "listoverridetable": true, "rsidtbl": true,
+	"generator": true, "xmlnstbl": true, "themedata": true, "datastore": true,
+	"latentstyles": true, "filetbl": true, "revtbl": true, "pgdsctbl": true,
+	"listtext": true, "pntext": true,
+}
+
+type rtfState struct {
+	skip      bool
+	ucSkip    int
+	outline   int
+	inList    bool
+	listLevel int
+}
+
+// extractRTF is a small tokenizer rather than a full RTF reader: it tracks
+// group state for skipped destinations, outline levels (headings) and list
+// membership, and decodes \'hh and \uN escapes.
+func extractRTF(ctx context.Context, data []byte) (*Document, error) {
+	src := string(data)
+	if !strings.HasPrefix(src, `{\rtf`) {
+		return nil, errors.New("not an rtf document")
+	}
+
+	doc := &Document{}
+	dec := charmap.Windows1252.NewDecoder()
+
+	var (
+		text    strings.Builder
+		stack   []rtfState
+		cur     = rtfState{outline: -1, ucSkip: 1}
+		pending int
+	)
+
+	endPara := func() {
+		switch {
+		case cur.outline >= 0:
+			doc.add(Heading, cur.outline+1, text.String())
+		case cur.inList:
+			doc.add(ListItem, cur.listLevel, text.String())
+		default:
+			doc.add(Paragraph, 0, text.String())
+		}
+		text.Reset()
+	}
+
+	emit := func(s string) {
+		if cur.skip {
+			return
+		}
+		if pending > 0 {
+			pending--
+			return
+		}
+		text.WriteString(s)
+	}
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch c {
+		case '{':
+			stack = append(stack, cur)
+			// a destination marked \* that we don't understand is skipped
+			if strings.HasPrefix(src[i+1:], `\*`) {
+				cur.skip = true
+			}
+		case '}':
+			if len(stack) > 0 {
+				cur = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case '\\':
+			if i+1 >= len(src) {
+				break
+			}
+			next := src[i+1]
+			switch {
+			case next == '\'' && i+3 < len(src):
+				if b, err := strconv.ParseUint(src[i+2:i+4], 16, 8); err == nil {
+					s, _ := dec.String(string([]byte{byte(b)}))
+					emit(s)
+				}
+				i += 3
+			case next == '\\' || next == '{' || next == '}':
+				emit(string(next))
+				i++
+			case next == '~':
+				emit(" ")
+				i++
+			case next == '-' || next == '_':
+				i++
+			case isASCIILetter(next):
+				j := i + 1
+				for j < len(src) && isASCIILetter(src[j]) {
+					j++
+				}
+				word := src[i+1 : j]
+				k := j
+				if k < len(src) && (src[k] == '-' || isDigit(src[k])) {
+					k++
+					for k < len(src) && isDigit(src[k]) {
+						k++
+					}
+				}
+				param := 0
+				if k > j {
+					param, _ = strconv.Atoi(src[j:k])
+				}
+				if k < len(src) && src[k] == ' ' {
+					k++
+				}
+				i = k - 1
+
+				switch {
+				case rtfSkipDestinations[word]:
+					cur.skip = true
+				case word == "par" || word == "sect" || word == "page":
+					if !cur.skip {
+						endPara()
+					}
+				case word == "pard":
+					cur.outline = -1
+					cur.inList = false
+					cur.listLevel = 0
+				case word == "outlinelevel":
+					cur.outline = param
+				case word == "ls" || word == "pnlvlblt" || word == "pnlvlbody":
+					cur.inList = true
+				case word == "ilvl":
+					cur.listLevel = param
+				case word == "line" || word == "tab" || word == "cell" || word == "row":
+					emit(" ")
+				case word == "uc":
+					cur.ucSkip = param
+				case word == "u":
+					if param < 0 {
+						param += 65536
+					}
+					emit(string(rune(param)))
+					pending = cur.ucSkip
+				case word == "emdash":
+					emit("—")
+				case word == "endash":
+					emit("–")
+				case word == "bullet":
+					emit("•")
+				case word == "lquote" || word == "rquote":
+					emit("'")
+				case word == "ldblquote" || word == "rdblquote":
+					emit(`"`)
+				}
+			default:
+				i++
+			}
+		case '\r', '\n':
+		default:
+			emit(string(c))
+		}
+	}
+	endPara()
+
+	return doc, nil
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,360 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	MimePPTX = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	MimeODP  = "application/vnd.oasis.opendocument.presentation"
+)
+
+func init() {
+	Register(MimePPTX, ExtractorFunc(extractSlides(MimePPTX)))
+	Register(MimeODP, ExtractorFunc(extractSlides(MimeODP)))
+}
+
+type Slide struct {
+	Number int     `json:"number"`
+	Title  string  `json:"title,omitempty"`
+	Body   []Block `json:"body,omitempty"`
+	Notes  string  `json:"notes,omitempty"`
+}
+
+type Deck struct {
+	Slides []Slide `json:"slides"`
+}
+
+func ParseSlides(mime string, data []byte) (*Deck, error) {
+	switch mime {
+	case MimePPTX:
+		return parsePPTX(data)
+	case MimeODP:
+		return parseODP(data)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupported, mime)
+}
+
+// extractSlides renders each slide as a "Slide N" heading followed by its
+// body and speaker notes, so the summarizer can cite slide numbers.
+func extractSlides(mime string) func(context.Context, []byte) (*Document, error) {
+	return func(ctx context.Context, data []byte) (*Document, error) {
+		deck, err := ParseSlides(mime, data)
+		if err != nil {
+			return nil, err
+		}
+
+		doc := &Document{PageCount: len(deck.Slides)}
+		for _, sl := range deck.Slides {
+			heading := fmt.Sprintf("Slide %d", sl.Number)
+			if sl.Title != "" {
+				heading += ": " + sl.Title
+			}
+			doc.add(Heading, 2, heading)
+			doc.Blocks = append(doc.Blocks, sl.Body...)
+			if sl.Notes != "" {
+				doc.add(Paragraph, 0, "Speaker notes: "+sl.Notes)
+			}
+		}
+		return doc, nil
+	}
+}
+
+type ooxmlRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Type   string `xml:"Type,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+func readRels(zr *zip.Reader, name string) (map[string]string, map[string]string) {
+	byID := map[string]string{}
+	byType := map[string]string{}
+
+	b, err := readZipMember(zr, name)
+	if err != nil {
+		return byID, byType
+	}
+	var rels ooxmlRels
+	if xml.Unmarshal(b, &rels) != nil {
+		return byID, byType
+	}
+	for _, r := range rels.Rels {
+		byID[r.ID] = r.Target
+		byType[path.Base(r.Type)] = r.Target
+	}
+	return byID, byType
+}
+
+// relID returns the r:id attribute, which shares its local name with the
+// plain id attribute on elements such as p:sldId.
+func relID(se xml.StartElement) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == "id" && strings.Contains(a.Name.Space, "relationships") {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func parsePPTX(data []byte) (*Deck, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	pres, err := readZipMember(zr, "ppt/presentation.xml")
+	if err != nil {
+		return nil, err
+	}
+	relsByID, _ := readRels(zr, "ppt/_rels/presentation.xml.rels")
+
+	// slide order lives in p:sldIdLst, not in the file names
+	var order []string
+	dec := xml.NewDecoder(bytes.NewReader(pres))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "sldId" {
+			if target, ok := relsByID[relID(se)]; ok {
+				order = append(order, path.Clean(path.Join("ppt", target)))
+			}
+		}
+	}
+
+	deck := &Deck{}
+	for i, slidePath := range order {
+		b, err := readZipMember(zr, slidePath)
+		if err != nil {
+			return nil, err
+		}
+		title, body, err := pptxShapes(b, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", slidePath, err)
+		}
+		sl := Slide{Number: i + 1, Title: title, Body: body}
+
+		relsPath := path.Join(path.Dir(slidePath), "_rels", path.Base(slidePath)+".rels")
+		_, relsByType := readRels(zr, relsPath)
+		if target, ok := relsByType["notesSlide"]; ok {
+			if nb, err := readZipMember(zr, path.Clean(path.Join(path.Dir(slidePath), target))); err == nil {
+				if _, notes, err := pptxShapes(nb, true); err == nil {
+					var parts []string
+					for _, n := range notes {
+						parts = append(parts, n.Text)
+					}
+					sl.Notes = strings.Join(parts, " ")
+				}
+			}
+		}
+
+		deck.Slides = append(deck.Slides, sl)
+	}
+	return deck, nil
+}
+
+// pptxShapes walks the p:sp shapes of a slide. The title placeholder becomes
+// the slide title; every other paragraph becomes a block, with bulleted
+// paragraphs (a:pPr lvl) turned into list items. For notes slides only the
+// body placeholder is kept, which skips the slide-image and page-number shapes.
+func pptxShapes(data []byte, notesOnly bool) (string, []Block, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	var (
+		title     []string
+		blocks    []Block
+		phType    string
+		hasPh     bool
+		inShape   bool
+		inText    bool
+		text      strings.Builder
+		lvl       = -1
+		hasBullet bool
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sp":
+				inShape = true
+				phType, hasPh = "", false
+			case "ph":
+				hasPh = true
+				phType = attr(t, "type")
+			case "p":
+				text.Reset()
+				lvl, hasBullet = -1, false
+			case "pPr":
+				if v := attr(t, "lvl"); v != "" {
+					lvl, _ = strconv.Atoi(v)
+				}
+			case "buChar", "buAutoNum":
+				hasBullet = true
+			case "t":
+				inText = true
+			case "br", "tab":
+				text.WriteString(" ")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "sp":
+				inShape = false
+			case "t":
+				inText = false
+			case "p":
+				if !inShape {
+					continue
+				}
+				line := strings.TrimSpace(text.String())
+				if line == "" {
+					continue
+				}
+				isTitle := phType == "title" || phType == "ctrTitle"
+				switch {
+				case notesOnly && (!hasPh || phType != "body"):
+				case phType == "dt" || phType == "sldNum" || phType == "ftr" || phType == "hdr":
+				case isTitle:
+					title = append(title, line)
+				case lvl > 0 || hasBullet || (hasPh && (phType == "" || phType == "body")):
+					if lvl < 0 {
+						lvl = 0
+					}
+					blocks = append(blocks, Block{Kind: ListItem, Level: lvl, Text: line})
+				default:
+					blocks = append(blocks, Block{Kind: Paragraph, Text: line})
+				}
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+
+	return strings.Join(title, " "), blocks, nil
+}
+
+func parseODP(data []byte) (*Deck, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+	content, err := readZipMember(zr, "content.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	deck := &Deck{}
+
+	var (
+		cur       *Slide
+		class     string
+		inNotes   bool
+		listDepth = -1
+		paraDepth int
+		text      strings.Builder
+		notes     []string
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "page":
+				cur = &Slide{Number: len(deck.Slides) + 1}
+				notes = nil
+			case "notes":
+				inNotes = true
+			case "frame":
+				class = attr(t, "class")
+			case "list":
+				listDepth++
+			case "p", "h":
+				if paraDepth == 0 {
+					text.Reset()
+				}
+				paraDepth++
+			case "s", "tab", "line-break":
+				text.WriteString(" ")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "page":
+				if cur != nil {
+					cur.Notes = strings.Join(notes, " ")
+					deck.Slides = append(deck.Slides, *cur)
+				}
+				cur = nil
+			case "notes":
+				inNotes = false
+			case "frame":
+				class = ""
+			case "list":
+				listDepth--
+			case "p", "h":
+				paraDepth--
+				if paraDepth > 0 || cur == nil {
+					continue
+				}
+				line := strings.Join(strings.Fields(text.String()), " ")
+				if line == "" {
+					continue
+				}
+				switch {
+				case inNotes:
+					if class != "page" {
+						notes = append(notes, line)
+					}
+				case class == "title":
+					if cur.Title != "" {
+						cur.Title += " "
+					}
+					cur.Title += line
+				case listDepth >= 0 || class == "outline":
+					lvl := listDepth
+					if lvl < 0 {
+						lvl = 0
+					}
+					cur.Body = append(cur.Body, Block{Kind: ListItem, Level: lvl, Text: line})
+				default:
+					cur.Body = append(cur.Body, Block{Kind: Paragraph, Text: line})
+				}
+			}
+		case xml.CharData:
+			if paraDepth > 0 {
+				text.Write(t)
+			}
+		}
+	}
+
+	return deck, nil
+}
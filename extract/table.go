@@ -0,0 +1,155 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+)
+
+// Table is a table block; its cells live in Block.Table rather than Block.Text.
+const Table BlockKind = "table"
+
+// MaxTableRows caps how many data rows of a table are rendered into Text().
+// The full table stays available through Document.Tables.
+var MaxTableRows = 200
+
+type TableData struct {
+	Title string `json:"title,omitempty"`
+	// Page is the 1-based source page for formats that have pages.
+	Page int        `json:"page,omitempty"`
+	Rows [][]string `json:"rows"`
+}
+
+// addTable normalizes cell whitespace, drops empty rows and trailing empty
+// columns, and pads every row to the same width.
+func (d *Document) addTable(page int, title string, rows [][]string) {
+	var clean [][]string
+	width := 0
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		last := -1
+		for i, c := range row {
+			cells[i] = strings.Join(strings.Fields(c), " ")
+			if cells[i] != "" {
+				last = i
+			}
+		}
+		if last < 0 {
+			continue
+		}
+		cells = cells[:last+1]
+		if len(cells) > width {
+			width = len(cells)
+		}
+		clean = append(clean, cells)
+	}
+	if len(clean) == 0 {
+		return
+	}
+	for i, row := range clean {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		clean[i] = row
+	}
+
+	d.Blocks = append(d.Blocks, Block{
+		Kind:  Table,
+		Page:  page,
+		Table: &TableData{Title: title, Page: page, Rows: clean},
+	})
+}
+
+// Tables returns every table of the document in order.
+func (d *Document) Tables() []TableData {
+	var tables []TableData
+	for _, b := range d.Blocks {
+		if b.Kind == Table && b.Table != nil {
+			tables = append(tables, *b.Table)
+		}
+	}
+	return tables
+}
+
+// Markdown renders the table as a pipe table with the first row as header.
+func (t *TableData) Markdown() string {
+	if len(t.Rows) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	writeRow := func(row []string) {
+		sb.WriteString("|")
+		for _, c := range row {
+			sb.WriteString(" ")
+			sb.WriteString(strings.ReplaceAll(c, "|", `\|`))
+			sb.WriteString(" |")
+		}
+		sb.WriteString("\n")
+	}
+
+	writeRow(t.Rows[0])
+	sb.WriteString("|")
+	sb.WriteString(strings.Repeat(" --- |", len(t.Rows[0])))
+	sb.WriteString("\n")
+
+	body := t.Rows[1:]
+	if len(body) > MaxTableRows {
+		body = body[:MaxTableRows]
+	}
+	for _, row := range body {
+		writeRow(row)
+	}
+	if rest := len(t.Rows) - 1 - len(body); rest > 0 {
+		fmt.Fprintf(&sb, "\n(%d more rows not shown)\n", rest)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (t *TableData) CSV() string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.WriteAll(t.Rows)
+	return buf.String()
+}
+
+const minTableRows = 3
+
+// addTextWithTables turns the text of one page into blocks, pulling out runs of
+// tab-separated lines as tables. A run needs at least minTableRows lines with
+// two or more cells; rows with missing cells are padded at the end.
+func (d *Document) addTextWithTables(page int, text string) {
+	var run [][]string
+	var prose []string
+
+	flushProse := func() {
+		for _, para := range splitParagraphs(strings.Join(prose, "\n")) {
+			d.addOnPage(page, Paragraph, 0, para)
+		}
+		prose = nil
+	}
+	flushRun := func() {
+		if len(run) >= minTableRows {
+			flushProse()
+			d.addTable(page, "", run)
+		} else {
+			for _, cells := range run {
+				prose = append(prose, strings.Join(cells, " "))
+			}
+		}
+		run = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		cells := strings.Split(line, "\t")
+		if len(cells) >= 2 {
+			run = append(run, cells)
+			continue
+		}
+		flushRun()
+		prose = append(prose, line)
+	}
+	flushRun()
+	flushProse()
+}
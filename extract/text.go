@@ -0,0 +1,42 @@
+package extract
+
+import (
+	"context"
+	"strings"
+)
+
+func init() {
+	Register(MimeText, ExtractorFunc(extractText))
+}
+
+func extractText(ctx context.Context, data []byte) (*Document, error) {
+	doc := &Document{}
+	for _, para := range splitParagraphs(string(data)) {
+		doc.add(Paragraph, 0, para)
+	}
+	return doc, nil
+}
+
+func splitParagraphs(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+
+	var paras []string
+	var cur strings.Builder
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if cur.Len() > 0 {
+				paras = append(paras, cur.String())
+				cur.Reset()
+			}
+			continue
+		}
+		if cur.Len() > 0 {
+			cur.WriteString(" ")
+		}
+		cur.WriteString(line)
+	}
+	if cur.Len() > 0 {
+		paras = append(paras, cur.String())
+	}
+	return paras
+}
@@ -0,0 +1,257 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const MimeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// xlsxMaxColumns is the column count of Excel sheets, A through XFD. Cells
+// past it are dropped, so a crafted reference cannot pad a row without end.
+const xlsxMaxColumns = 16384
+
+// xlsxMaxCells caps the cells of one sheet, counting the blanks that pad
+// rows out to their referenced columns.
+const xlsxMaxCells = 4 << 20
+
+func init() {
+	Register(MimeXLSX, ExtractorFunc(extractXLSX))
+}
+
+type Sheet struct {
+	Name string     `json:"name"`
+	Rows [][]string `json:"rows"`
+}
+
+// extractXLSX renders every worksheet as a heading followed by its table.
+// Cells are taken as stored, so dates show up as Excel serial numbers.
+func extractXLSX(ctx context.Context, data []byte) (*Document, error) {
+	sheets, err := ParseXLSX(data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	for _, sh := range sheets {
+		doc.add(Heading, 2, "Sheet: "+sh.Name)
+		doc.addTable(0, sh.Name, sh.Rows)
+	}
+	return doc, nil
+}
+
+// ParseXLSX returns the worksheets of a workbook in tab order.
+func ParseXLSX(data []byte) ([]Sheet, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	wb, err := readZipMember(zr, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	relsByID, _ := readRels(zr, "xl/_rels/workbook.xml.rels")
+
+	shared, err := xlsxSharedStrings(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var sheets []Sheet
+	dec := xml.NewDecoder(bytes.NewReader(wb))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "sheet" {
+			continue
+		}
+		target, ok := relsByID[relID(se)]
+		if !ok {
+			continue
+		}
+		// targets are relative to xl/ unless they start at the package root
+		sheetPath := path.Clean(path.Join("xl", target))
+		if strings.HasPrefix(target, "/") {
+			sheetPath = strings.TrimPrefix(target, "/")
+		}
+
+		b, err := readZipMember(zr, sheetPath)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := xlsxRows(b, shared)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", sheetPath, err)
+		}
+		sheets = append(sheets, Sheet{Name: attr(se, "name"), Rows: rows})
+	}
+	return sheets, nil
+}
+
+func xlsxSharedStrings(zr *zip.Reader) ([]string, error) {
+	b, err := readZipMember(zr, "xl/sharedStrings.xml")
+	if err != nil {
+		// workbooks with only numbers or inline strings have no shared strings part
+		return nil, nil
+	}
+
+	var (
+		strs    []string
+		cur     strings.Builder
+		inText  bool
+		inPhone bool
+	)
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				cur.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				// phonetic guides repeat the text in kana
+				inPhone = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				strs = append(strs, cur.String())
+			case "t":
+				inText = false
+			case "rPh":
+				inPhone = false
+			}
+		case xml.CharData:
+			if inText && !inPhone {
+				cur.Write(t)
+			}
+		}
+	}
+	return strs, nil
+}
+
+func xlsxRows(data []byte, shared []string) ([][]string, error) {
+	var (
+		rows     [][]string
+		row      []string
+		col      int
+		cellType string
+		value    strings.Builder
+		inValue  bool
+		skip     bool
+		cells    int
+	)
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				row = nil
+				col = 0
+			case "c":
+				skip = false
+				if ref := attr(t, "r"); ref != "" {
+					c, ok := columnIndex(ref)
+					if ok {
+						col = c
+					}
+					skip = !ok
+				}
+				skip = skip || col >= xlsxMaxColumns
+				cellType = attr(t, "t")
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				if skip {
+					continue
+				}
+				cells += max(col-len(row), 0) + 1
+				if cells > xlsxMaxCells {
+					return nil, fmt.Errorf("sheet has more than %d cells", xlsxMaxCells)
+				}
+				for len(row) < col {
+					row = append(row, "")
+				}
+				row = append(row, xlsxCellValue(cellType, value.String(), shared))
+				col++
+			case "row":
+				rows = append(rows, row)
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+	return rows, nil
+}
+
+func xlsxCellValue(cellType, raw string, shared []string) string {
+	switch cellType {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || i < 0 || i >= len(shared) {
+			return ""
+		}
+		return shared[i]
+	case "b":
+		if raw == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return raw
+}
+
+// columnIndex turns the letters of a cell reference such as "AB12" into a
+// 0-based column index. References past column XFD are rejected.
+func columnIndex(ref string) (int, bool) {
+	n := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		n = n*26 + int(ref[i]-'A'+1)
+		if n > xlsxMaxColumns {
+			return 0, false
+		}
+	}
+	if i == 0 {
+		return 0, false
+	}
+	return n - 1, true
+}
@@ -0,0 +1,43 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// maxZipMember caps how much of a single archive member we are willing to
+// inflate, so a crafted office file cannot blow up memory.
+const maxZipMember = 64 << 20
+
+func openZip(data []byte) (*zip.Reader, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	return zr, nil
+}
+
+func readZipMember(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer rc.Close()
+
+		b, err := io.ReadAll(io.LimitReader(rc, maxZipMember+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if len(b) > maxZipMember {
+			return nil, fmt.Errorf("%s is too large", name)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("%s not found in archive", name)
+}
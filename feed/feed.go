@@ -0,0 +1,239 @@
+// Package feed parses RSS 2.0 and Atom feeds down to what a podcast
+// subscription needs: the feed's title and its episodes' media enclosures.
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+var ErrNotFeed = errors.New("document is not an rss or atom feed")
+
+type Feed struct {
+	Title string
+	Link  string
+	Items []Item
+}
+
+// Item is one episode. GUID falls back to the enclosure URL for feeds that
+// leave it out.
+type Item struct {
+	GUID          string
+	Title         string
+	Link          string
+	Published     time.Time
+	EnclosureURL  string
+	EnclosureType string
+}
+
+type rssDoc struct {
+	Channel struct {
+		Title string `xml:"title"`
+		Link  string `xml:"link"`
+		Items []struct {
+			Title     string `xml:"title"`
+			Link      string `xml:"link"`
+			GUID      string `xml:"guid"`
+			PubDate   string `xml:"pubDate"`
+			Enclosure struct {
+				URL  string `xml:"url,attr"`
+				Type string `xml:"type,attr"`
+			} `xml:"enclosure"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type atomDoc struct {
+	Title   string     `xml:"title"`
+	Links   []atomLink `xml:"link"`
+	Entries []struct {
+		ID        string     `xml:"id"`
+		Title     string     `xml:"title"`
+		Published string     `xml:"published"`
+		Updated   string     `xml:"updated"`
+		Links     []atomLink `xml:"link"`
+	} `xml:"entry"`
+}
+
+// Parse reads an RSS or Atom document. Only items with an audio or video
+// enclosure are returned, newest first.
+func Parse(data []byte) (*Feed, error) {
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var f *Feed
+	switch root {
+	case "rss":
+		f, err = parseRSS(data)
+	case "feed":
+		f, err = parseAtom(data)
+	default:
+		return nil, ErrNotFeed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(f.Items, func(i, j int) bool {
+		return f.Items[i].Published.After(f.Items[j].Published)
+	})
+	return f, nil
+}
+
+func newDecoder(data []byte) *xml.Decoder {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.CharsetReader = charset.NewReaderLabel
+	// feeds in the wild are full of HTML entities
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	return dec
+}
+
+func rootElement(data []byte) (string, error) {
+	dec := newDecoder(data)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", ErrNotFeed
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se.Name.Local, nil
+		}
+	}
+}
+
+func parseRSS(data []byte) (*Feed, error) {
+	var doc rssDoc
+	if err := newDecoder(data).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse rss: %w", err)
+	}
+
+	f := &Feed{Title: strings.TrimSpace(doc.Channel.Title), Link: strings.TrimSpace(doc.Channel.Link)}
+	for _, it := range doc.Channel.Items {
+		enc := strings.TrimSpace(it.Enclosure.URL)
+		if !isMedia(enc, it.Enclosure.Type) {
+			continue
+		}
+		f.Items = append(f.Items, Item{
+			GUID:          firstNonEmpty(it.GUID, enc),
+			Title:         strings.TrimSpace(it.Title),
+			Link:          strings.TrimSpace(it.Link),
+			Published:     parseDate(it.PubDate),
+			EnclosureURL:  enc,
+			EnclosureType: it.Enclosure.Type,
+		})
+	}
+	return f, nil
+}
+
+func parseAtom(data []byte) (*Feed, error) {
+	var doc atomDoc
+	if err := newDecoder(data).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse atom: %w", err)
+	}
+
+	f := &Feed{Title: strings.TrimSpace(doc.Title)}
+	for _, l := range doc.Links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			f.Link = l.Href
+			break
+		}
+	}
+
+	for _, e := range doc.Entries {
+		var enc, encType, link string
+		for _, l := range e.Links {
+			switch l.Rel {
+			case "enclosure":
+				if enc == "" && isMedia(l.Href, l.Type) {
+					enc, encType = strings.TrimSpace(l.Href), l.Type
+				}
+			case "", "alternate":
+				link = l.Href
+			}
+		}
+		if enc == "" {
+			continue
+		}
+		f.Items = append(f.Items, Item{
+			GUID:          firstNonEmpty(e.ID, enc),
+			Title:         strings.TrimSpace(e.Title),
+			Link:          strings.TrimSpace(link),
+			Published:     parseDate(firstNonEmpty(e.Published, e.Updated)),
+			EnclosureURL:  enc,
+			EnclosureType: encType,
+		})
+	}
+	return f, nil
+}
+
+var mediaExts = map[string]bool{
+	".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".oga": true,
+	".opus": true, ".wav": true, ".flac": true, ".mp4": true, ".m4v": true,
+	".webm": true, ".mov": true,
+}
+
+// isMedia accepts enclosures typed as audio or video, and untyped ones whose
+// path looks like a media file.
+func isMedia(url, typ string) bool {
+	if url == "" {
+		return false
+	}
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if strings.HasPrefix(typ, "audio/") || strings.HasPrefix(typ, "video/") {
+		return true
+	}
+	if typ != "" && typ != "application/octet-stream" {
+		return false
+	}
+	p, _, _ := strings.Cut(url, "?")
+	return mediaExts[strings.ToLower(path.Ext(p))]
+}
+
+// dateLayouts covers RFC 822 dates as feeds actually write them, and the
+// RFC 3339 dates Atom requires.
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	time.RFC3339,
+	time.RFC3339Nano,
+}
+
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
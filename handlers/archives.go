@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/lupppig/briefly/media"
+	"github.com/lupppig/briefly/service"
+	"github.com/lupppig/briefly/utils"
+)
+
+// PostArchive takes a zip upload, expands it within the archive limits and
+// queues one child job per supported file. Progress and per-file results are
+// reported by GetBatch.
+func (b *BriefHandler) PostArchive(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxArchiveUploadBytes)
+
+	// anything past the in-memory part is spooled to disk
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		log.Println(err.Error())
+		utils.FerrorResponse(w, http.StatusBadRequest, "file too large", err.Error())
+		return
+	}
+	fi, fh, err := r.FormFile("file")
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "missing file", err.Error())
+		return
+	}
+	defer fi.Close()
+
+	transcriber, err := service.ParseTranscriber(r.FormValue("transcriber"))
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid transcriber", err.Error())
+		return
+	}
+	preprocess, err := media.ParsePreprocess(r.FormValue("preprocess"))
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid preprocessing", err.Error())
+		return
+	}
+	combine := false
+	if v := r.FormValue("combined_summary"); v != "" {
+		if combine, err = strconv.ParseBool(v); err != nil {
+			utils.FerrorResponse(w, http.StatusBadRequest, "invalid combined_summary", err.Error())
+			return
+		}
+	}
+
+	archive, err := service.ExpandArchive(fi, fh.Size, fh.Filename)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotArchive),
+			errors.Is(err, service.ErrArchiveUnsafePath),
+			errors.Is(err, service.ErrArchiveEntries),
+			errors.Is(err, service.ErrArchiveTooLarge),
+			errors.Is(err, service.ErrArchiveEmpty):
+			utils.FerrorResponse(w, http.StatusBadRequest, "invalid archive", err.Error())
+		default:
+			log.Printf("could not expand archive: %v", err)
+			utils.InternalServerResponse(w)
+		}
+		return
+	}
+
+	jobID := utils.NewJobID()
+	b.Serv.JobManager.CreateJob(jobID)
+
+	go b.Serv.ProcessArchiveJob(jobID, archive, service.BatchOptions{
+		Title:       r.FormValue("title"),
+		Combine:     combine,
+		Transcriber: transcriber,
+		Preprocess:  preprocess,
+	})
+
+	utils.JSONResponse(w, http.StatusOK, "ok", map[string]interface{}{
+		"job_id":  jobID,
+		"files":   len(archive.Entries),
+		"skipped": archive.Skipped,
+	})
+}
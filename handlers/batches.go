@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/lupppig/briefly/media"
+	"github.com/lupppig/briefly/service"
+	"github.com/lupppig/briefly/utils"
+)
+
+// PostBatch queues many links and previously uploaded files at once. Each
+// item becomes a child job; the batch job's progress counts them.
+func (b *BriefHandler) PostBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Title       string   `json:"title"`
+		Links       []string `json:"links"`
+		FileIDs     []string `json:"file_ids"`
+		Combine     bool     `json:"combined_summary"`
+		Transcriber string   `json:"transcriber"`
+		Preprocess  string   `json:"preprocess"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := service.CheckBatch(req.Links, req.FileIDs); err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid batch", err.Error())
+		return
+	}
+	for _, link := range req.Links {
+		if utils.IsYouTubeCollection(link) {
+			utils.FerrorResponse(w, http.StatusBadRequest, "invalid batch", "playlists and channels cannot be part of a batch: "+link)
+			return
+		}
+	}
+
+	transcriber, err := service.ParseTranscriber(req.Transcriber)
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid transcriber", err.Error())
+		return
+	}
+	preprocess, err := media.ParsePreprocess(req.Preprocess)
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid preprocessing", err.Error())
+		return
+	}
+
+	jobID := utils.NewJobID()
+	b.Serv.JobManager.CreateJob(jobID)
+
+	go b.Serv.ProcessBatchJob(jobID, req.Links, req.FileIDs, service.BatchOptions{
+		Title:       req.Title,
+		Combine:     req.Combine,
+		Transcriber: transcriber,
+		Preprocess:  preprocess,
+	})
+
+	utils.JSONResponse(w, http.StatusOK, "ok", map[string]string{"job_id": jobID})
+}
+
+// GetBatch reports a batch job like GetYoutubeJob does, and adds the current
+// state of every child job, so per-item results can be followed before the
+// whole batch has finished.
+func (b *BriefHandler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["job_id"]
+
+	job, ok := b.Serv.JobManager.Snapshot(jobID)
+	if !ok {
+		utils.FerrorResponse(w, http.StatusNotFound, "job not found", "")
+		return
+	}
+
+	type item struct {
+		JobID string `json:"job_id"`
+		service.JobStatus
+	}
+	items := make([]item, 0, len(job.Children))
+	for _, id := range job.Children {
+		child, _ := b.Serv.JobManager.Snapshot(id)
+		items = append(items, item{JobID: id, JobStatus: child})
+	}
+
+	utils.JSONResponse(w, http.StatusOK, "ok", map[string]interface{}{
+		"job":   job,
+		"items": items,
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lupppig/briefly/service"
+	"github.com/lupppig/briefly/utils"
+)
+
+// PostChannel starts watching a YouTube channel. interval is a duration such
+// as "30m" or "6h"; backfill queues that many of the latest uploads, and by
+// default only uploads published from now on are summarized.
+func (b *BriefHandler) PostChannel(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL      string `json:"url"`
+		Interval string `json:"interval"`
+		Backfill int    `json:"backfill"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	var interval time.Duration
+	if req.Interval != "" {
+		var err error
+		if interval, err = time.ParseDuration(req.Interval); err != nil || interval <= 0 {
+			utils.FerrorResponse(w, http.StatusBadRequest, "invalid interval", "interval must be a duration such as 30m or 6h")
+			return
+		}
+	}
+
+	cw, err := b.Serv.AddChannelWatch(r.Context(), req.URL, interval, req.Backfill)
+	if err != nil {
+		log.Printf("could not watch channel: %v", err)
+		switch {
+		case errors.Is(err, service.ErrChannelBackfill):
+			utils.FerrorResponse(w, http.StatusBadRequest, "invalid backfill", err.Error())
+		case errors.Is(err, service.ErrWatchInterval):
+			utils.FerrorResponse(w, http.StatusBadRequest, "invalid interval", err.Error())
+		case errors.Is(err, service.ErrNotChannel), !utils.IsYouTubeCollection(req.URL):
+			utils.FerrorResponse(w, http.StatusBadRequest, "invalid channel url", err.Error())
+		default:
+			utils.FerrorResponse(w, http.StatusBadGateway, "could not list channel", err.Error())
+		}
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, "ok", cw)
+}
+
+func (b *BriefHandler) GetChannels(w http.ResponseWriter, r *http.Request) {
+	watches, err := b.Db.ListChannelWatches(r.Context())
+	if err != nil {
+		log.Printf("could not list channel watches: %v", err)
+		utils.InternalServerResponse(w)
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, "ok", watches)
+}
+
+// GetChannelVideos lists the videos seen on a watched channel with their
+// status and, once done, their summary.
+func (b *BriefHandler) GetChannelVideos(w http.ResponseWriter, r *http.Request) {
+	watchID := mux.Vars(r)["watch_id"]
+
+	cw, err := b.Db.GetChannelWatch(r.Context(), watchID)
+	if err != nil {
+		log.Printf("could not get channel watch: %v", err)
+		utils.InternalServerResponse(w)
+		return
+	}
+	if cw == nil {
+		utils.FerrorResponse(w, http.StatusNotFound, "channel watch not found", "")
+		return
+	}
+
+	videos, err := b.Db.ListWatchVideos(r.Context(), watchID)
+	if err != nil {
+		log.Printf("could not list channel videos: %v", err)
+		utils.InternalServerResponse(w)
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, "ok", map[string]interface{}{"channel": cw, "videos": videos})
+}
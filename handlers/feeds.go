@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/lupppig/briefly/service"
+	"github.com/lupppig/briefly/utils"
+)
+
+// PostFeed registers a podcast feed. backfill queues that many of the latest
+// episodes; by default only episodes published from now on are summarized.
+func (b *BriefHandler) PostFeed(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL      string `json:"url"`
+		Backfill int    `json:"backfill"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	f, err := b.Serv.AddFeed(r.Context(), req.URL, req.Backfill)
+	if err != nil {
+		log.Printf("could not add feed: %v", err)
+		switch {
+		case errors.Is(err, service.ErrFeedBackfill):
+			utils.FerrorResponse(w, http.StatusBadRequest, "invalid backfill", err.Error())
+		case service.IsFeedError(err):
+			utils.FerrorResponse(w, http.StatusUnprocessableEntity, "not a podcast feed", err.Error())
+		default:
+			utils.FerrorResponse(w, http.StatusBadGateway, "could not fetch feed", err.Error())
+		}
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, "ok", f)
+}
+
+func (b *BriefHandler) GetFeeds(w http.ResponseWriter, r *http.Request) {
+	feeds, err := b.Db.ListFeeds(r.Context())
+	if err != nil {
+		log.Printf("could not list feeds: %v", err)
+		utils.InternalServerResponse(w)
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, "ok", feeds)
+}
+
+// GetFeedItems lists a feed's episodes with their status and, once done,
+// their summary.
+func (b *BriefHandler) GetFeedItems(w http.ResponseWriter, r *http.Request) {
+	feedID := mux.Vars(r)["feed_id"]
+
+	f, err := b.Db.GetFeed(r.Context(), feedID)
+	if err != nil {
+		log.Printf("could not get feed: %v", err)
+		utils.InternalServerResponse(w)
+		return
+	}
+	if f == nil {
+		utils.FerrorResponse(w, http.StatusNotFound, "feed not found", "")
+		return
+	}
+
+	items, err := b.Db.ListFeedItems(r.Context(), feedID)
+	if err != nil {
+		log.Printf("could not list feed items: %v", err)
+		utils.InternalServerResponse(w)
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, "ok", map[string]interface{}{"feed": f, "items": items})
+}
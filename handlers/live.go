@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lupppig/briefly/media"
+	"github.com/lupppig/briefly/service"
+	"github.com/lupppig/briefly/utils"
+)
+
+// maxLiveFrame caps one websocket message; a second of 48 kHz stereo PCM is
+// under 200 KB.
+const maxLiveFrame = 1 << 20
+
+var liveUpgrader = websocket.Upgrader{
+	ReadBufferSize:  32 * 1024,
+	WriteBufferSize: 16 * 1024,
+}
+
+// LiveTranscribe streams audio in and transcript events out over a
+// websocket. Binary messages are audio frames in the format given by the
+// query (codec=pcm|opus, sample_rate, channels); a text message "stop" or
+// closing the socket ends the session, which is then summarized and stored.
+// Optional query parameters: title, and summary_minutes for the rolling
+// summary interval.
+func (b *BriefHandler) LiveTranscribe(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts := service.LiveOptions{
+		Format: media.StreamFormat{Codec: media.CodecPCM, SampleRate: 16000, Channels: 1},
+		Title:  q.Get("title"),
+	}
+	if codec := q.Get("codec"); codec != "" {
+		opts.Format.Codec = codec
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{
+		{"sample_rate", &opts.Format.SampleRate},
+		{"channels", &opts.Format.Channels},
+	} {
+		if v := q.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				utils.FerrorResponse(w, http.StatusBadRequest, "invalid "+p.name, err.Error())
+				return
+			}
+			*p.dst = n
+		}
+	}
+	if err := opts.Format.Validate(); err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid audio format", err.Error())
+		return
+	}
+	if v := q.Get("summary_minutes"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			utils.FerrorResponse(w, http.StatusBadRequest, "invalid summary_minutes", "summary_minutes must be a positive number")
+			return
+		}
+		opts.SummaryEvery = time.Duration(n) * time.Minute
+	}
+
+	conn, err := liveUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already answered the request
+		log.Printf("could not upgrade live session: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	send := func(e service.LiveEvent) {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if err := conn.WriteJSON(e); err != nil {
+			log.Printf("could not send live event: %v", err)
+		}
+	}
+
+	// the session outlives the request context, since it is still summarized
+	// and stored after the client has gone
+	ctx := context.Background()
+	ls, err := b.Serv.StartLiveSession(ctx, opts, send)
+	if err != nil {
+		log.Printf("could not start live session: %v", err)
+		send(service.LiveEvent{Type: service.LiveError, Error: "could not start transcription"})
+		return
+	}
+
+	conn.SetReadLimit(maxLiveFrame)
+	conn.SetReadDeadline(time.Now().Add(service.MaxLiveSession))
+	for {
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("live session ended: %v", err)
+			}
+			break
+		}
+		if kind == websocket.TextMessage {
+			if string(data) == "stop" {
+				break
+			}
+			continue
+		}
+		if err := ls.Write(data); err != nil {
+			msg := "could not decode audio"
+			if errors.Is(err, service.ErrLiveBacklog) {
+				msg = err.Error()
+			}
+			log.Printf("live session stopped: %v", err)
+			// the session's goroutines may be sending too, so every event
+			// goes through its serialized sender from here on
+			ls.Send(service.LiveEvent{Type: service.LiveError, Error: msg})
+			break
+		}
+	}
+
+	content, err := ls.Close(ctx)
+	if err != nil {
+		log.Printf("could not save live session: %v", err)
+		msg := "could not save the session"
+		if errors.Is(err, service.ErrEmptySession) {
+			msg = err.Error()
+		}
+		ls.Send(service.LiveEvent{Type: service.LiveError, Error: msg})
+		return
+	}
+	ls.Send(service.LiveEvent{Type: service.LiveDone, Content: content})
+	// Close has stopped the session's goroutines, so nothing else writes now
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
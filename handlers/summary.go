@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
@@ -9,6 +11,8 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/lupppig/briefly/db/mini"
 	db "github.com/lupppig/briefly/db/postgres"
+	"github.com/lupppig/briefly/extract"
+	"github.com/lupppig/briefly/media"
 	"github.com/lupppig/briefly/service"
 	"github.com/lupppig/briefly/utils"
 )
@@ -21,7 +25,13 @@ type BriefHandler struct {
 
 func (b *BriefHandler) PostYoutube(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Link string `json:"link"`
+		Link             string `json:"link"`
+		Start            string `json:"start"`
+		End              string `json:"end"`
+		Keyframes        bool   `json:"keyframes"`
+		CaptionKeyframes bool   `json:"caption_keyframes"`
+		Transcriber      string `json:"transcriber"`
+		Preprocess       string `json:"preprocess"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -29,20 +39,128 @@ func (b *BriefHandler) PostYoutube(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	rng, err := parseTimeRange(req.Start, req.End)
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid time range", err.Error())
+		return
+	}
+	transcriber, err := service.ParseTranscriber(req.Transcriber)
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid transcriber", err.Error())
+		return
+	}
+	preprocess, err := media.ParsePreprocess(req.Preprocess)
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid preprocessing", err.Error())
+		return
+	}
+
+	// playlist and channel links fan out into one child job per video
+	collection := utils.IsYouTubeCollection(req.Link)
+	if collection && !rng.IsZero() {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid time range", "time ranges only apply to single videos")
+		return
+	}
+	if collection && (req.Keyframes || req.CaptionKeyframes) {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid request", "keyframes only apply to single videos")
+		return
+	}
+
+	jobID := utils.NewJobID()
+	b.Serv.JobManager.CreateJob(jobID)
+
+	if collection {
+		go b.Serv.ProcessYoutubeCollectionJob(jobID, req.Link, service.MediaOptions{
+			Transcriber: transcriber,
+			Preprocess:  preprocess,
+		})
+	} else {
+		go b.Serv.ProcessYoutubeJob(jobID, req.Link, service.MediaOptions{
+			Range:            rng,
+			Keyframes:        req.Keyframes || req.CaptionKeyframes,
+			CaptionKeyframes: req.CaptionKeyframes,
+			Transcriber:      transcriber,
+			Preprocess:       preprocess,
+		})
+	}
+
+	utils.JSONResponse(w, http.StatusOK, "ok", map[string]string{"job_id": jobID})
+}
+
+func (b *BriefHandler) PostURL(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL string `json:"url"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	jobID := utils.NewJobID()
+	b.Serv.JobManager.CreateJob(jobID)
+
+	go b.Serv.ProcessURLJob(jobID, req.URL)
+
+	utils.JSONResponse(w, http.StatusOK, "ok", map[string]string{"job_id": jobID})
+}
+
+// PostMedia summarizes a video or audio link from any supported platform:
+// YouTube, Vimeo, direct media files or anything else yt-dlp can fetch.
+func (b *BriefHandler) PostMedia(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Link             string `json:"link"`
+		Start            string `json:"start"`
+		End              string `json:"end"`
+		Keyframes        bool   `json:"keyframes"`
+		CaptionKeyframes bool   `json:"caption_keyframes"`
+		Transcriber      string `json:"transcriber"`
+		Preprocess       string `json:"preprocess"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	rng, err := parseTimeRange(req.Start, req.End)
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid time range", err.Error())
+		return
+	}
+	transcriber, err := service.ParseTranscriber(req.Transcriber)
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid transcriber", err.Error())
+		return
+	}
+	preprocess, err := media.ParsePreprocess(req.Preprocess)
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid preprocessing", err.Error())
+		return
+	}
+
 	jobID := utils.NewJobID()
 	b.Serv.JobManager.CreateJob(jobID)
 
-	go b.Serv.ProcessYoutubeJob(jobID, req.Link)
+	go b.Serv.ProcessMediaJob(jobID, req.Link, service.MediaOptions{
+		Range:            rng,
+		Keyframes:        req.Keyframes || req.CaptionKeyframes,
+		CaptionKeyframes: req.CaptionKeyframes,
+		Transcriber:      transcriber,
+		Preprocess:       preprocess,
+	})
 
 	utils.JSONResponse(w, http.StatusOK, "ok", map[string]string{"job_id": jobID})
 }
 
+// GetYoutubeJob reports the status of any background job; URL jobs share the
+// same job manager. Playlist jobs also list their child jobs and progress.
 func (b *BriefHandler) GetYoutubeJob(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	jobID := vars["job_id"]
 
-	job := b.Serv.JobManager.GetJob(jobID)
-	if job == nil {
+	job, ok := b.Serv.JobManager.Snapshot(jobID)
+	if !ok {
 		utils.FerrorResponse(w, http.StatusNotFound, "job not found", "")
 		return
 	}
@@ -67,18 +185,130 @@ func (b *BriefHandler) PostAudioDoc(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	serv := service.Service{Db: b.Db, Mc: b.Mclient}
-	doc, err := serv.AudioDocService(fi, fh)
+	rng, err := parseTimeRange(r.FormValue("start"), r.FormValue("end"))
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid time range", err.Error())
+		return
+	}
+
+	transcriber, err := service.ParseTranscriber(r.FormValue("transcriber"))
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid transcriber", err.Error())
+		return
+	}
+	preprocess, err := media.ParsePreprocess(r.FormValue("preprocess"))
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid preprocessing", err.Error())
+		return
+	}
+
+	opts := service.UploadOptions{
+		PDFPassword: r.PostFormValue("password"),
+		Range:       rng,
+		Transcriber: transcriber,
+		Preprocess:  preprocess,
+	}
+	stored, audioKey, err := b.Serv.StoreUpload(fi, fh, opts)
+	if err != nil {
+		uploadError(w, err)
+		return
+	}
+	// transcribing a recording or summarizing a book chapter by chapter takes
+	// far longer than a request may run, so those are summarized by a job
+	if stored.FileType == "audio" || stored.FileType == "video" || stored.MimeType == extract.MimeEPUB {
+		jobID := utils.NewJobID()
+		b.Serv.JobManager.CreateJob(jobID)
+		go b.Serv.ProcessFileJob(jobID, stored.ID, opts)
+		utils.JSONResponse(w, http.StatusOK, "ok", map[string]string{"job_id": jobID, "file_id": stored.ID})
+		return
+	}
 
+	doc, err := b.Serv.SummarizeUpload(stored, audioKey, opts)
 	if err != nil {
-		log.Printf("could not upload file: %v", err)
-		if strings.Contains(err.Error(), "unsupported") {
-			utils.FerrorResponse(w, http.StatusBadRequest, err.Error(), "")
-			return
+		uploadError(w, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, "upload successful", doc)
+
+}
+
+// uploadError reports a failed upload with the status its cause calls for.
+func uploadError(w http.ResponseWriter, err error) {
+	log.Printf("could not upload file: %v", err)
+	if strings.Contains(err.Error(), "unsupported") {
+		utils.FerrorResponse(w, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+	if errors.Is(err, extract.ErrPDFPasswordRequired) {
+		utils.FerrorResponse(w, http.StatusUnprocessableEntity, "this pdf is password protected", extract.ErrPDFPasswordRequired.Error())
+		return
+	}
+	if errors.Is(err, extract.ErrPDFPasswordInvalid) {
+		utils.FerrorResponse(w, http.StatusUnprocessableEntity, "the pdf password is incorrect", extract.ErrPDFPasswordInvalid.Error())
+		return
+	}
+	if errors.Is(err, service.ErrRangeNotMedia) || errors.Is(err, service.ErrRangeOutOfBounds) {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid time range", err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrPreprocessDoc) {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid preprocessing", err.Error())
+		return
+	}
+	if errors.Is(err, extract.ErrDOCUnavailable) {
+		utils.FerrorResponse(w, http.StatusUnsupportedMediaType, "unsupported document format", err.Error())
+		return
+	}
+	if errors.Is(err, extract.ErrOCRUnavailable) {
+		utils.FerrorResponse(w, http.StatusUnsupportedMediaType, "images cannot be read on this server", err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrNoText) {
+		utils.FerrorResponse(w, http.StatusUnprocessableEntity, err.Error(), "")
+		return
+	}
+	utils.InternalServerResponse(w)
+}
+
+// parseTimeRange reads the optional start/end of a media request; both empty
+// is the whole recording.
+func parseTimeRange(start, end string) (media.TimeRange, error) {
+	var rng media.TimeRange
+	var err error
+	if start != "" {
+		if rng.Start, err = utils.ParseTimestamp(start); err != nil {
+			return rng, fmt.Errorf("start: %w", err)
 		}
+	}
+	if end != "" {
+		if rng.End, err = utils.ParseTimestamp(end); err != nil {
+			return rng, fmt.Errorf("end: %w", err)
+		}
+	}
+	return rng, rng.Validate()
+}
+
+// GetKeyframe serves a keyframe image referenced by a summary timeline.
+func (b *BriefHandler) GetKeyframe(w http.ResponseWriter, r *http.Request) {
+	kf, err := b.Db.GetKeyframe(r.Context(), mux.Vars(r)["keyframe_id"])
+	if err != nil {
+		log.Printf("could not get keyframe: %v", err)
+		utils.InternalServerResponse(w)
+		return
+	}
+	if kf == nil {
+		utils.FerrorResponse(w, http.StatusNotFound, "keyframe not found", "")
+		return
+	}
+
+	buf, err := b.Mclient.GetObjectBuffer(mini.DocumentBucket, kf.ObjectKey)
+	if err != nil {
+		log.Printf("could not read keyframe: %v", err)
 		utils.InternalServerResponse(w)
 		return
 	}
-	utils.JSONResponse(w, http.StatusOK, "upload successful", doc)
 
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "max-age=86400")
+	w.Write(buf.Bytes())
 }
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/lupppig/briefly/utils"
+)
+
+func (b *BriefHandler) GetFileTables(w http.ResponseWriter, r *http.Request) {
+	fileID := mux.Vars(r)["file_id"]
+
+	tables, err := b.Db.GetContentTables(r.Context(), fileID)
+	if err != nil {
+		log.Printf("could not get tables: %v", err)
+		utils.InternalServerResponse(w)
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, "ok", tables)
+}
+
+// GetFileTable returns one table as JSON, or as a CSV download with
+// ?format=csv.
+func (b *BriefHandler) GetFileTable(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	position, err := strconv.Atoi(vars["position"])
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid table position", err.Error())
+		return
+	}
+
+	table, err := b.Db.GetContentTable(r.Context(), vars["file_id"], position)
+	if err != nil {
+		log.Printf("could not get table: %v", err)
+		utils.InternalServerResponse(w)
+		return
+	}
+	if table == nil {
+		utils.FerrorResponse(w, http.StatusNotFound, "table not found", "")
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"table-"+strconv.Itoa(position)+".csv\"")
+		cw := csv.NewWriter(w)
+		cw.WriteAll(table.Rows)
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, "ok", table)
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/lupppig/briefly/media"
+	"github.com/lupppig/briefly/service"
+	"github.com/lupppig/briefly/utils"
+)
+
+// The resumable upload endpoints speak tus 1.0.0 with the creation and
+// termination extensions, so any tus client can upload to them.
+const tusVersion = "1.0.0"
+
+// tusHeaders sets the headers every tus response carries and reports whether
+// the request speaks a version we do; OPTIONS is exempt.
+func tusHeaders(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if r.Method == http.MethodOptions || r.Header.Get("Tus-Resumable") == tusVersion {
+		return true
+	}
+	w.Header().Set("Tus-Version", tusVersion)
+	utils.FerrorResponse(w, http.StatusPreconditionFailed, "unsupported tus version", "expected Tus-Resumable: "+tusVersion)
+	return false
+}
+
+// TusOptions describes what the upload endpoint supports.
+func (b *BriefHandler) TusOptions(w http.ResponseWriter, r *http.Request) {
+	tusHeaders(w, r)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", "creation,termination")
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(service.MaxResumableUpload, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseTusMetadata decodes an Upload-Metadata header: comma-separated
+// "key base64(value)" pairs, where the value may be left out.
+func parseTusMetadata(header string) (map[string]string, error) {
+	md := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("metadata %q is not base64", key)
+		}
+		md[key] = string(value)
+	}
+	return md, nil
+}
+
+// uploadOptionsFromMetadata reads the processing options a tus client sends
+// as upload metadata: start, end, transcriber and preprocess, the same as
+// the fields of a regular upload.
+func uploadOptionsFromMetadata(md map[string]string) (service.UploadOptions, error) {
+	rng, err := parseTimeRange(md["start"], md["end"])
+	if err != nil {
+		return service.UploadOptions{}, err
+	}
+	transcriber, err := service.ParseTranscriber(md["transcriber"])
+	if err != nil {
+		return service.UploadOptions{}, err
+	}
+	preprocess, err := media.ParsePreprocess(md["preprocess"])
+	if err != nil {
+		return service.UploadOptions{}, err
+	}
+	return service.UploadOptions{Range: rng, Transcriber: transcriber, Preprocess: preprocess}, nil
+}
+
+// TusCreate registers an upload. Upload-Length is required, and so is a
+// filename in Upload-Metadata; a sha256 entry there is checked once the
+// upload is complete.
+func (b *BriefHandler) TusCreate(w http.ResponseWriter, r *http.Request) {
+	if !tusHeaders(w, r) {
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid Upload-Length", "Upload-Length is required")
+		return
+	}
+	if length > service.MaxResumableUpload {
+		utils.FerrorResponse(w, http.StatusRequestEntityTooLarge, "upload too large", service.ErrUploadLength.Error())
+		return
+	}
+	md, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid Upload-Metadata", err.Error())
+		return
+	}
+	filename := md["filename"]
+	if filename == "" {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid Upload-Metadata", "filename is required")
+		return
+	}
+	checksum := md["sha256"]
+	if checksum != "" {
+		if raw, err := hex.DecodeString(checksum); err != nil || len(raw) != sha256.Size {
+			utils.FerrorResponse(w, http.StatusBadRequest, "invalid Upload-Metadata", "sha256 must be 64 hex characters")
+			return
+		}
+	}
+	if _, err := uploadOptionsFromMetadata(md); err != nil {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid Upload-Metadata", err.Error())
+		return
+	}
+
+	u, err := b.Serv.CreateResumableUpload(r.Context(), filename, length, checksum, md)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUploadLength), errors.Is(err, service.ErrUploadDocument):
+			utils.FerrorResponse(w, http.StatusRequestEntityTooLarge, "upload too large", err.Error())
+		case strings.Contains(err.Error(), "unsupported"):
+			utils.FerrorResponse(w, http.StatusBadRequest, err.Error(), "")
+		default:
+			log.Printf("could not create upload: %v", err)
+			utils.InternalServerResponse(w)
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/api/uploads/"+u.ID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// TusHead reports how much of an upload has been received.
+func (b *BriefHandler) TusHead(w http.ResponseWriter, r *http.Request) {
+	if !tusHeaders(w, r) {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	u, offset, err := b.Serv.ResumableUpload(r.Context(), mux.Vars(r)["upload_id"])
+	if err != nil {
+		if errors.Is(err, service.ErrUploadNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		log.Printf("could not get upload: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// TusPatch appends a chunk at Upload-Offset. The request that completes the
+// upload starts processing; its job ID is returned in Upload-Job-Id and is
+// also reported by GetUpload. If that request fails once every byte is in,
+// an empty PATCH at the final offset completes the upload.
+func (b *BriefHandler) TusPatch(w http.ResponseWriter, r *http.Request) {
+	if !tusHeaders(w, r) {
+		return
+	}
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		utils.FerrorResponse(w, http.StatusUnsupportedMediaType, "invalid Content-Type", "expected application/offset+octet-stream")
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		utils.FerrorResponse(w, http.StatusBadRequest, "invalid Upload-Offset", "Upload-Offset is required")
+		return
+	}
+
+	id := mux.Vars(r)["upload_id"]
+	newOffset, done, err := b.Serv.WriteResumableChunk(r.Context(), id, offset, r.Body)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUploadNotFound):
+			utils.FerrorResponse(w, http.StatusNotFound, err.Error(), "")
+		case errors.Is(err, service.ErrUploadOffset), errors.Is(err, service.ErrUploadDone):
+			utils.FerrorResponse(w, http.StatusConflict, err.Error(), "")
+		case errors.Is(err, service.ErrUploadBusy):
+			utils.FerrorResponse(w, http.StatusLocked, err.Error(), "")
+		case errors.Is(err, service.ErrUploadOverrun):
+			utils.FerrorResponse(w, http.StatusRequestEntityTooLarge, err.Error(), "")
+		default:
+			// the client resumes from the offset it gets from HEAD
+			log.Printf("could not write upload chunk: %v", err)
+			utils.InternalServerResponse(w)
+		}
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	if done {
+		u, _, err := b.Serv.ResumableUpload(r.Context(), id)
+		if err != nil {
+			log.Printf("could not get upload: %v", err)
+			utils.InternalServerResponse(w)
+			return
+		}
+		// the options were checked when the upload was created
+		opts, _ := uploadOptionsFromMetadata(u.Metadata)
+
+		jobID := utils.NewJobID()
+		b.Serv.JobManager.CreateJob(jobID)
+		go b.Serv.ProcessResumableUploadJob(jobID, id, opts)
+		w.Header().Set("Upload-Job-Id", jobID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TusDelete terminates an upload that has not completed.
+func (b *BriefHandler) TusDelete(w http.ResponseWriter, r *http.Request) {
+	if !tusHeaders(w, r) {
+		return
+	}
+	err := b.Serv.DeleteResumableUpload(r.Context(), mux.Vars(r)["upload_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUploadNotFound):
+			utils.FerrorResponse(w, http.StatusNotFound, err.Error(), "")
+		case errors.Is(err, service.ErrUploadDone):
+			utils.FerrorResponse(w, http.StatusConflict, err.Error(), "")
+		default:
+			log.Printf("could not delete upload: %v", err)
+			utils.InternalServerResponse(w)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUpload reports an upload as JSON, including the ID of the job that
+// processes it once it is complete.
+func (b *BriefHandler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	u, offset, err := b.Serv.ResumableUpload(r.Context(), mux.Vars(r)["upload_id"])
+	if err != nil {
+		if errors.Is(err, service.ErrUploadNotFound) {
+			utils.FerrorResponse(w, http.StatusNotFound, err.Error(), "")
+			return
+		}
+		log.Printf("could not get upload: %v", err)
+		utils.InternalServerResponse(w)
+		return
+	}
+	u.Offset = offset
+	utils.JSONResponse(w, http.StatusOK, "ok", u)
+}
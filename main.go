@@ -6,13 +6,17 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/lupppig/briefly/db/mini"
 	db "github.com/lupppig/briefly/db/postgres"
+	"github.com/lupppig/briefly/extract"
 	"github.com/lupppig/briefly/handlers"
+	"github.com/lupppig/briefly/outbound"
 	"github.com/lupppig/briefly/service"
 )
 
@@ -26,6 +30,34 @@ func main() {
 	secretAccesskey := os.Getenv("SECRET_ACCESSKEY")
 	useSSL := os.Getenv("USE_SSL") != "0"
 
+	if backend := os.Getenv("PDF_TEXT_BACKEND"); backend != "" {
+		extract.PDFBackend = backend
+	}
+	if timeout, err := time.ParseDuration(os.Getenv("PDF_EXTRACT_TIMEOUT")); err == nil {
+		extract.PDFTimeout = timeout
+	}
+	if hosts := os.Getenv("OUTBOUND_ALLOW_HOSTS"); hosts != "" {
+		outbound.Default.AllowHosts = strings.Split(hosts, ",")
+	}
+	if hosts := os.Getenv("OUTBOUND_DENY_HOSTS"); hosts != "" {
+		outbound.Default.DenyHosts = strings.Split(hosts, ",")
+	}
+	if lang := os.Getenv("OCR_LANGUAGE"); lang != "" {
+		extract.OCRLanguage = lang
+	}
+	if conf, err := strconv.ParseFloat(os.Getenv("OCR_MIN_CONFIDENCE"), 64); err == nil {
+		extract.OCRMinConfidence = conf
+	}
+	if os.Getenv("PREFER_CAPTIONS") == "1" {
+		service.PreferCaptions = true
+	}
+	if interval, err := time.ParseDuration(os.Getenv("FEED_POLL_INTERVAL")); err == nil && interval > 0 {
+		service.FeedPollInterval = interval
+	}
+	if interval, err := time.ParseDuration(os.Getenv("CHANNEL_WATCH_INTERVAL")); err == nil && interval > 0 {
+		service.ChannelWatchInterval = interval
+	}
+
 	r := mux.NewRouter()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -53,7 +85,37 @@ func main() {
 
 	r.HandleFunc("/api/youtube", h.PostYoutube)
 	r.HandleFunc("/api/file", h.PostAudioDoc)
+	r.HandleFunc("/api/file/{job_id}", h.GetYoutubeJob)
 	r.HandleFunc("/api/youtube/{job_id}", h.GetYoutubeJob)
+	r.HandleFunc("/api/url", h.PostURL)
+	r.HandleFunc("/api/url/{job_id}", h.GetYoutubeJob)
+	r.HandleFunc("/api/media", h.PostMedia)
+	r.HandleFunc("/api/media/{job_id}", h.GetYoutubeJob)
+	r.HandleFunc("/api/keyframes/{keyframe_id}", h.GetKeyframe)
+	r.HandleFunc("/api/live", h.LiveTranscribe)
+	r.HandleFunc("/api/batches", h.PostBatch).Methods(http.MethodPost)
+	r.HandleFunc("/api/batches/{job_id}", h.GetBatch)
+	r.HandleFunc("/api/archives", h.PostArchive).Methods(http.MethodPost)
+	r.HandleFunc("/api/archives/{job_id}", h.GetBatch)
+	r.HandleFunc("/api/uploads", h.TusOptions).Methods(http.MethodOptions)
+	r.HandleFunc("/api/uploads", h.TusCreate).Methods(http.MethodPost)
+	r.HandleFunc("/api/uploads/{upload_id}", h.TusHead).Methods(http.MethodHead)
+	r.HandleFunc("/api/uploads/{upload_id}", h.TusPatch).Methods(http.MethodPatch)
+	r.HandleFunc("/api/uploads/{upload_id}", h.TusDelete).Methods(http.MethodDelete)
+	r.HandleFunc("/api/uploads/{upload_id}", h.GetUpload).Methods(http.MethodGet)
+	r.HandleFunc("/api/files/{file_id}/tables", h.GetFileTables)
+	r.HandleFunc("/api/files/{file_id}/tables/{position}", h.GetFileTable)
+	r.HandleFunc("/api/feeds", h.PostFeed).Methods(http.MethodPost)
+	r.HandleFunc("/api/feeds", h.GetFeeds).Methods(http.MethodGet)
+	r.HandleFunc("/api/feeds/{feed_id}/items", h.GetFeedItems)
+	r.HandleFunc("/api/channels", h.PostChannel).Methods(http.MethodPost)
+	r.HandleFunc("/api/channels", h.GetChannels).Methods(http.MethodGet)
+	r.HandleFunc("/api/channels/{watch_id}/videos", h.GetChannelVideos)
+
+	s.StartFeedPoller(context.Background(), service.FeedPollInterval)
+	s.StartChannelWatcher(context.Background())
+	s.StartUploadJanitor(context.Background())
+	s.JobManager.StartEviction(context.Background(), service.JobTTL)
 
 	srv := &http.Server{
 		Handler:      r,
@@ -0,0 +1,167 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/lupppig/briefly/outbound"
+	"github.com/lupppig/briefly/utils"
+)
+
+// MaxDirectMediaBytes caps the download of a direct media link.
+var MaxDirectMediaBytes int64 = 1 << 30
+
+var ErrMediaTooLarge = errors.New("remote media file is too large")
+
+// directMediaExts are the file extensions that mark a link as a plain media
+// file rather than a page that embeds one.
+var directMediaExts = map[string]bool{
+	".mp3": true, ".m4a": true, ".aac": true, ".wav": true, ".flac": true,
+	".ogg": true, ".oga": true, ".opus": true, ".mp4": true, ".m4v": true,
+	".webm": true, ".mkv": true, ".mov": true,
+}
+
+var directVideoExts = map[string]bool{
+	".mp4": true, ".m4v": true, ".webm": true, ".mkv": true, ".mov": true,
+}
+
+// DirectMedia handles links straight to an audio or video file, such as
+// podcast enclosures. The file is downloaded with the outbound client, so
+// neither yt-dlp nor ffmpeg ever opens the URL.
+type DirectMedia struct{}
+
+func (DirectMedia) Name() string { return "direct" }
+
+func (DirectMedia) Validate(link string) error {
+	u, err := utils.NormalizeURL(link)
+	if err != nil {
+		return err
+	}
+	pu, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	if !directMediaExts[strings.ToLower(path.Ext(pu.Path))] {
+		return errors.New("not a direct media link")
+	}
+	return nil
+}
+
+func (DirectMedia) CanonicalID(link string) (*Source, error) {
+	u, err := utils.NormalizeURL(link)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{Provider: "direct", ExternalID: u, URL: u}, nil
+}
+
+// Metadata only knows the file name; the duration is probed after download.
+func (DirectMedia) Metadata(ctx context.Context, src *Source) (*SourceMetadata, error) {
+	u, err := url.Parse(src.URL)
+	if err != nil {
+		return nil, err
+	}
+	name, err := url.PathUnescape(path.Base(u.Path))
+	if err != nil {
+		name = path.Base(u.Path)
+	}
+	return &SourceMetadata{Title: strings.TrimSuffix(name, path.Ext(name))}, nil
+}
+
+func (DirectMedia) FetchAudio(ctx context.Context, src *Source, dir string) (string, error) {
+	downloaded, err := downloadMedia(ctx, src.URL, dir)
+	if err != nil {
+		return "", err
+	}
+	if src.Range.IsZero() {
+		return downloaded, nil
+	}
+
+	// plain files cannot be fetched in part reliably, so the range is cut
+	// out after the download
+	trimmed := filepath.Join(dir, "trimmed.wav")
+	if err := TrimAudio(ctx, downloaded, trimmed, src.Range); err != nil {
+		return "", err
+	}
+	return trimmed, nil
+}
+
+// FetchVideo only works for links to video containers; audio files return
+// ErrNoVideo without being downloaded.
+func (DirectMedia) FetchVideo(ctx context.Context, src *Source, dir string) (string, error) {
+	u, err := url.Parse(src.URL)
+	if err != nil {
+		return "", err
+	}
+	if !directVideoExts[strings.ToLower(path.Ext(u.Path))] {
+		return "", ErrNoVideo
+	}
+
+	downloaded, err := downloadMedia(ctx, src.URL, dir)
+	if err != nil {
+		return "", err
+	}
+	if src.Range.IsZero() {
+		return downloaded, nil
+	}
+
+	trimmed := filepath.Join(dir, "trimmed"+path.Ext(downloaded))
+	if err := trimVideo(ctx, downloaded, trimmed, src.Range); err != nil {
+		return "", err
+	}
+	return trimmed, nil
+}
+
+// downloadMedia saves the file at link into dir with the outbound client.
+func downloadMedia(ctx context.Context, link, dir string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "briefly/1.0 (+https://github.com/lupppig/briefly)")
+
+	resp, err := outbound.Client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch media: %s", resp.Status)
+	}
+	if ct, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && strings.HasPrefix(ct, "text/") {
+		return "", fmt.Errorf("media link returned %s", ct)
+	}
+	if resp.ContentLength > MaxDirectMediaBytes {
+		return "", ErrMediaTooLarge
+	}
+
+	u, _ := url.Parse(link)
+	out, err := os.Create(filepath.Join(dir, "source"+strings.ToLower(path.Ext(u.Path))))
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	n, err := io.Copy(out, io.LimitReader(resp.Body, MaxDirectMediaBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to download media: %w", err)
+	}
+	if n > MaxDirectMediaBytes {
+		return "", ErrMediaTooLarge
+	}
+	return out.Name(), nil
+}
+
+func (DirectMedia) FetchCaptions(ctx context.Context, src *Source) (string, error) {
+	return "", ErrNoCaptions
+}
@@ -0,0 +1,67 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+type ffprobeOutput struct {
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"format"`
+	Streams []struct {
+		CodecType  string `json:"codec_type"`
+		CodecName  string `json:"codec_name"`
+		SampleRate string `json:"sample_rate"`
+		Channels   int    `json:"channels"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"streams"`
+}
+
+// FFProbe shells out to ffprobe and reports the first audio stream.
+func FFProbe(ctx context.Context, path string) (*Info, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		"ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		path,
+	)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var probe ffprobeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, fmt.Errorf("ffprobe returned invalid json: %w", err)
+	}
+
+	dur, err := strconv.ParseFloat(probe.Format.Duration, 64)
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe returned invalid duration %q: %w", probe.Format.Duration, err)
+	}
+
+	info := &Info{Format: probe.Format.FormatName, Duration: dur}
+	info.Bitrate, _ = strconv.ParseInt(probe.Format.BitRate, 10, 64)
+
+	for _, st := range probe.Streams {
+		if st.CodecType != "audio" {
+			continue
+		}
+		info.Codec = st.CodecName
+		info.Channels = st.Channels
+		info.SampleRate, _ = strconv.Atoi(st.SampleRate)
+		break
+	}
+
+	return info, nil
+}
@@ -0,0 +1,41 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+func probeFLAC(r io.ReadSeeker, size int64) (*Info, error) {
+	if _, err := r.Seek(4, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// STREAMINFO is required to be the first metadata block
+	hdr := make([]byte, 4+34)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, fmt.Errorf("flac: read streaminfo: %w", err)
+	}
+	if hdr[0]&0x7F != 0 {
+		return nil, errors.New("flac: first metadata block is not streaminfo")
+	}
+
+	si := hdr[4:]
+	packed := binary.BigEndian.Uint64(si[10:18])
+	sampleRate := int(packed >> 44)
+	channels := int((packed>>41)&0x07) + 1
+	totalSamples := packed & 0xFFFFFFFFF
+
+	if sampleRate == 0 {
+		return nil, errors.New("flac: invalid sample rate")
+	}
+
+	return &Info{
+		Format:     "flac",
+		Codec:      "flac",
+		Duration:   float64(totalSamples) / float64(sampleRate),
+		SampleRate: sampleRate,
+		Channels:   channels,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	// KeyframeSceneThreshold is the ffmpeg scene score (0 to 1) above which a
+	// frame counts as a new scene. Slides and screen recordings change little
+	// between scenes, so it is kept low.
+	KeyframeSceneThreshold = 0.3
+	// MinKeyframeGap drops scene changes that follow the previous keyframe
+	// too closely, such as transitions and camera cuts.
+	MinKeyframeGap = 5 * time.Second
+	// MaxKeyframes caps how many keyframes one recording yields.
+	MaxKeyframes = 40
+)
+
+var ErrNoVideo = errors.New("source has no video")
+
+// VideoFetcher is implemented by providers that can download an item's video
+// stream, which keyframe extraction needs.
+type VideoFetcher interface {
+	// FetchVideo downloads a video of src into dir, at a resolution good
+	// enough for stills, and returns its path. Only src.Range is returned when
+	// it is set.
+	FetchVideo(ctx context.Context, src *Source, dir string) (string, error)
+}
+
+// Keyframe is a still extracted at a scene change. Time is in seconds of the
+// original recording.
+type Keyframe struct {
+	Time float64
+	Path string
+}
+
+var ptsTimeRegex = regexp.MustCompile(`pts_time:\s*([0-9.]+)`)
+
+// ExtractKeyframes writes a JPEG into dir for the first frame of videoPath and
+// for each scene change after it. offset is added to the frame times, for
+// videos that were cut from a longer recording.
+func ExtractKeyframes(ctx context.Context, videoPath, dir string, offset time.Duration) ([]Keyframe, error) {
+	filter := fmt.Sprintf("select='eq(n\\,0)+gt(scene\\,%s)',showinfo,scale='min(640\\,iw)':-2",
+		strconv.FormatFloat(KeyframeSceneThreshold, 'f', -1, 64))
+	out, err := exec.CommandContext(ctx,
+		"ffmpeg",
+		"-y",
+		"-i", videoPath,
+		"-an",
+		"-vf", filter,
+		"-fps_mode", "vfr",
+		"-q:v", "4",
+		filepath.Join(dir, "frame_%04d.jpg"),
+	).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg keyframes failed: %v\noutput: %s", err, out)
+	}
+
+	// showinfo logs one line per selected frame, in the order they are written
+	var frames []Keyframe
+	last := -1.0
+	for i, m := range ptsTimeRegex.FindAllSubmatch(out, -1) {
+		path := filepath.Join(dir, fmt.Sprintf("frame_%04d.jpg", i+1))
+		t, err := strconv.ParseFloat(string(m[1]), 64)
+		if err != nil {
+			continue
+		}
+		if len(frames) >= MaxKeyframes || (last >= 0 && t-last < MinKeyframeGap.Seconds()) {
+			os.Remove(path)
+			continue
+		}
+		last = t
+		frames = append(frames, Keyframe{Time: t + offset.Seconds(), Path: path})
+	}
+	if len(frames) == 0 {
+		return nil, ErrNoVideo
+	}
+	return frames, nil
+}
+
+// ytdlpVideo downloads a video stream of at most 720p, with no audio, since
+// only stills are taken from it.
+func ytdlpVideo(ctx context.Context, link, dir string, r TimeRange) (string, error) {
+	args := []string{
+		"-f", "bestvideo[height<=720]/best[height<=720]/bestvideo/best",
+		"-o", filepath.Join(dir, "video.%(ext)s"),
+	}
+	if !r.IsZero() {
+		args = append(args, "--download-sections", r.ytdlpSection())
+	}
+	if _, err := runYTDLP(ctx, link, args...); err != nil {
+		return "", err
+	}
+	return firstMatch(filepath.Join(dir, "video.*"))
+}
@@ -0,0 +1,165 @@
+package media
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var mp3Bitrates = [2][3][16]int{
+	// MPEG-1: layer I, II, III
+	{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	},
+	// MPEG-2 and 2.5: layer I, II, III
+	{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	},
+}
+
+var mp3SampleRates = map[int][3]int{
+	3: {44100, 48000, 32000}, // MPEG-1
+	2: {22050, 24000, 16000}, // MPEG-2
+	0: {11025, 12000, 8000},  // MPEG-2.5
+}
+
+type mp3Frame struct {
+	version    int
+	layer      int
+	bitrate    int
+	sampleRate int
+	channels   int
+	samples    int
+	length     int
+}
+
+func parseMP3Frame(h []byte) (mp3Frame, bool) {
+	if h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+
+	version := int(h[1]>>3) & 0x03
+	layerBits := int(h[1]>>1) & 0x03
+	brIdx := int(h[2]>>4) & 0x0F
+	srIdx := int(h[2]>>2) & 0x03
+	padding := int(h[2]>>1) & 0x01
+	mode := int(h[3]>>6) & 0x03
+
+	if version == 1 || layerBits == 0 || brIdx == 0 || brIdx == 15 || srIdx == 3 {
+		return mp3Frame{}, false
+	}
+
+	layer := 4 - layerBits
+	v := 0
+	if version != 3 {
+		v = 1
+	}
+
+	f := mp3Frame{
+		version:    version,
+		layer:      layer,
+		bitrate:    mp3Bitrates[v][layer-1][brIdx] * 1000,
+		sampleRate: mp3SampleRates[version][srIdx],
+		channels:   2,
+	}
+	if mode == 3 {
+		f.channels = 1
+	}
+
+	switch {
+	case layer == 1:
+		f.samples = 384
+		f.length = (12*f.bitrate/f.sampleRate + padding) * 4
+	case layer == 3 && v == 1:
+		f.samples = 576
+		f.length = 72*f.bitrate/f.sampleRate + padding
+	default:
+		f.samples = 1152
+		f.length = 144*f.bitrate/f.sampleRate + padding
+	}
+	return f, f.length > 4
+}
+
+// probeMP3 walks every frame header instead of decoding the audio, which
+// keeps VBR files accurate without the cost of a full decode.
+func probeMP3(r io.ReadSeeker, size int64) (*Info, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+
+	head := make([]byte, 10)
+	if _, err := io.ReadFull(br, head); err != nil {
+		return nil, err
+	}
+
+	if string(head[:3]) == "ID3" {
+		tagSize := int64(head[6]&0x7F)<<21 | int64(head[7]&0x7F)<<14 | int64(head[8]&0x7F)<<7 | int64(head[9]&0x7F)
+		if head[5]&0x10 != 0 {
+			tagSize += 10
+		}
+		if _, err := br.Discard(int(tagSize)); err != nil {
+			return nil, errors.New("mp3: truncated ID3 tag")
+		}
+	} else {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		br.Reset(r)
+	}
+
+	var (
+		first   *mp3Frame
+		resync  int
+		frames  int64
+		samples int64
+		audio   int64
+	)
+
+	hdr := make([]byte, 4)
+	for {
+		b, err := br.Peek(4)
+		if err != nil {
+			break
+		}
+		copy(hdr, b)
+
+		f, ok := parseMP3Frame(hdr)
+		if !ok {
+			// resync one byte at a time; give up if the stream never had a frame
+			if first == nil && resync > 64*1024 {
+				return nil, errors.New("mp3: no frame sync found")
+			}
+			br.Discard(1)
+			resync++
+			continue
+		}
+		if first == nil {
+			fc := f
+			first = &fc
+		}
+
+		frames++
+		samples += int64(f.samples)
+		audio += int64(f.length)
+		if _, err := br.Discard(f.length); err != nil {
+			break
+		}
+	}
+
+	if first == nil || frames == 0 {
+		return nil, errors.New("mp3: no frames found")
+	}
+
+	duration := float64(samples) / float64(first.sampleRate)
+	return &Info{
+		Format:     "mp3",
+		Codec:      fmt.Sprintf("mp%d", first.layer),
+		Duration:   duration,
+		SampleRate: first.sampleRate,
+		Channels:   first.channels,
+		Bitrate:    int64(float64(audio*8) / duration),
+	}, nil
+}
@@ -0,0 +1,185 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+type mp4Box struct {
+	typ   string
+	start int64
+	size  int64
+	body  int64
+}
+
+func readMP4Box(r io.ReadSeeker, pos, end int64) (mp4Box, error) {
+	if _, err := r.Seek(pos, io.SeekStart); err != nil {
+		return mp4Box{}, err
+	}
+	hdr := make([]byte, 8)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return mp4Box{}, err
+	}
+
+	b := mp4Box{
+		typ:   string(hdr[4:8]),
+		start: pos,
+		size:  int64(binary.BigEndian.Uint32(hdr[:4])),
+		body:  pos + 8,
+	}
+	switch b.size {
+	case 0:
+		b.size = end - pos
+	case 1:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return mp4Box{}, err
+		}
+		b.size = int64(binary.BigEndian.Uint64(ext))
+		b.body += 8
+	}
+	if b.size < b.body-b.start || pos+b.size > end {
+		return mp4Box{}, errors.New("mp4: malformed box")
+	}
+	return b, nil
+}
+
+// findMP4Box returns the first child of the given type inside [pos, end).
+func findMP4Box(r io.ReadSeeker, pos, end int64, typ string) (mp4Box, bool) {
+	for pos+8 <= end {
+		b, err := readMP4Box(r, pos, end)
+		if err != nil {
+			return mp4Box{}, false
+		}
+		if b.typ == typ {
+			return b, true
+		}
+		pos += b.size
+	}
+	return mp4Box{}, false
+}
+
+func mp4Path(r io.ReadSeeker, parent mp4Box, path ...string) (mp4Box, bool) {
+	b := parent
+	for _, typ := range path {
+		var ok bool
+		if b, ok = findMP4Box(r, b.body, b.start+b.size, typ); !ok {
+			return mp4Box{}, false
+		}
+	}
+	return b, true
+}
+
+// probeMP4 reads the duration from mvhd and the audio parameters from the
+// first audio track's sample description.
+func probeMP4(r io.ReadSeeker, size int64) (*Info, error) {
+	root := mp4Box{start: 0, size: size, body: 0}
+	moov, ok := mp4Path(r, root, "moov")
+	if !ok {
+		return nil, errors.New("mp4: missing moov box")
+	}
+
+	info := &Info{Format: "mp4"}
+
+	if mvhd, ok := mp4Path(r, moov, "mvhd"); ok {
+		info.Duration = readMP4Duration(r, mvhd)
+	}
+
+	pos := moov.body
+	for pos+8 <= moov.start+moov.size {
+		trak, err := readMP4Box(r, pos, moov.start+moov.size)
+		if err != nil {
+			break
+		}
+		pos += trak.size
+		if trak.typ != "trak" {
+			continue
+		}
+
+		hdlr, ok := mp4Path(r, trak, "mdia", "hdlr")
+		if !ok {
+			continue
+		}
+		handler := make([]byte, 4)
+		r.Seek(hdlr.body+8, io.SeekStart)
+		if _, err := io.ReadFull(r, handler); err != nil || string(handler) != "soun" {
+			continue
+		}
+
+		if info.Duration == 0 {
+			if mdhd, ok := mp4Path(r, trak, "mdia", "mdhd"); ok {
+				info.Duration = readMP4Duration(r, mdhd)
+			}
+		}
+
+		stsd, ok := mp4Path(r, trak, "mdia", "minf", "stbl", "stsd")
+		if !ok {
+			break
+		}
+		// full box header (4) + entry count (4), then the first sample entry
+		entry, err := readMP4Box(r, stsd.body+8, stsd.start+stsd.size)
+		if err != nil {
+			break
+		}
+		info.Codec = mp4Codec(entry.typ)
+
+		// SampleEntry (8) + reserved (8), then channelcount, samplesize, pre_defined, reserved, samplerate 16.16
+		desc := make([]byte, 20)
+		r.Seek(entry.body, io.SeekStart)
+		if _, err := io.ReadFull(r, desc); err == nil {
+			info.Channels = int(binary.BigEndian.Uint16(desc[16:18]))
+		}
+		rate := make([]byte, 4)
+		r.Seek(entry.body+24, io.SeekStart)
+		if _, err := io.ReadFull(r, rate); err == nil {
+			info.SampleRate = int(binary.BigEndian.Uint32(rate) >> 16)
+		}
+		break
+	}
+
+	if info.Duration == 0 {
+		return nil, errors.New("mp4: no duration found")
+	}
+	return info, nil
+}
+
+func readMP4Duration(r io.ReadSeeker, b mp4Box) float64 {
+	r.Seek(b.body, io.SeekStart)
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0
+	}
+
+	var timescale uint32
+	var duration uint64
+	if buf[0] == 1 {
+		timescale = binary.BigEndian.Uint32(buf[20:24])
+		duration = binary.BigEndian.Uint64(buf[24:32])
+	} else {
+		timescale = binary.BigEndian.Uint32(buf[12:16])
+		duration = uint64(binary.BigEndian.Uint32(buf[16:20]))
+	}
+	if timescale == 0 {
+		return 0
+	}
+	return float64(duration) / float64(timescale)
+}
+
+func mp4Codec(fourcc string) string {
+	switch fourcc {
+	case "mp4a":
+		return "aac"
+	case "alac":
+		return "alac"
+	case "Opus":
+		return "opus"
+	case "fLaC":
+		return "flac"
+	case "ac-3":
+		return "ac3"
+	case "ec-3":
+		return "eac3"
+	}
+	return fourcc
+}
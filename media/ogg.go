@@ -0,0 +1,92 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// probeOgg identifies Vorbis or Opus from the first packet and takes the
+// duration from the granule position of the last page.
+func probeOgg(r io.ReadSeeker, size int64) (*Info, error) {
+	first := make([]byte, 27+255+64)
+	n, err := io.ReadFull(r, first)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	first = first[:n]
+	if n < 27 {
+		return nil, errors.New("ogg: truncated page")
+	}
+
+	segments := int(first[26])
+	if len(first) < 27+segments {
+		return nil, errors.New("ogg: truncated page")
+	}
+	packet := first[27+segments:]
+
+	info := &Info{Format: "ogg"}
+	var preSkip uint16
+	var granuleRate float64
+
+	switch {
+	case len(packet) >= 30 && packet[0] == 1 && bytes.Equal(packet[1:7], []byte("vorbis")):
+		info.Codec = "vorbis"
+		info.Channels = int(packet[11])
+		info.SampleRate = int(binary.LittleEndian.Uint32(packet[12:16]))
+		info.Bitrate = int64(int32(binary.LittleEndian.Uint32(packet[20:24])))
+		granuleRate = float64(info.SampleRate)
+	case len(packet) >= 19 && bytes.Equal(packet[:8], []byte("OpusHead")):
+		info.Codec = "opus"
+		info.Channels = int(packet[9])
+		preSkip = binary.LittleEndian.Uint16(packet[10:12])
+		info.SampleRate = int(binary.LittleEndian.Uint32(packet[12:16]))
+		// opus granule positions always count 48 kHz samples
+		granuleRate = 48000
+	default:
+		return nil, errors.New("ogg: unsupported codec")
+	}
+	if info.Bitrate < 0 {
+		info.Bitrate = 0
+	}
+
+	granule, err := lastOggGranule(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if granule > uint64(preSkip) {
+		granule -= uint64(preSkip)
+	}
+	if granuleRate > 0 {
+		info.Duration = float64(granule) / granuleRate
+	}
+	return info, nil
+}
+
+func lastOggGranule(r io.ReadSeeker, size int64) (uint64, error) {
+	const window = 64 * 1024
+	start := size - window
+	if start < 0 {
+		start = 0
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return 0, err
+	}
+	tail := make([]byte, size-start)
+	if _, err := io.ReadFull(r, tail); err != nil {
+		return 0, err
+	}
+
+	idx := bytes.LastIndex(tail, []byte("OggS"))
+	for idx >= 0 {
+		if idx+14 <= len(tail) {
+			g := binary.LittleEndian.Uint64(tail[idx+6 : idx+14])
+			if g != ^uint64(0) {
+				return g, nil
+			}
+		}
+		idx = bytes.LastIndex(tail[:idx], []byte("OggS"))
+	}
+	return 0, errors.New("ogg: no granule position found")
+}
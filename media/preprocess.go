@@ -0,0 +1,163 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// HighPassHz is the cutoff of the high-pass filter, which removes rumble,
+	// handling noise and mains hum below speech.
+	HighPassHz = 100
+	// DenoiseFloor is the noise floor in dB that afftdn reduces towards.
+	DenoiseFloor = -25
+	// LoudnessTarget is the integrated loudness loudnorm aims for, in LUFS.
+	LoudnessTarget = -16.0
+)
+
+// Preprocess selects the filters applied to audio before transcription. The
+// zero value leaves the audio untouched.
+type Preprocess struct {
+	HighPass  bool
+	Denoise   bool
+	Normalize bool
+}
+
+// preprocessSteps are the accepted names, in the order the filters run:
+// noise is removed before loudness is measured and normalized.
+var preprocessSteps = []string{"highpass", "denoise", "loudnorm"}
+
+// ParsePreprocess reads a comma-separated list of steps ("highpass",
+// "denoise", "loudnorm") or "all". An empty string is no preprocessing.
+func ParsePreprocess(s string) (Preprocess, error) {
+	var p Preprocess
+	for _, step := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(step)) {
+		case "":
+		case "highpass":
+			p.HighPass = true
+		case "denoise":
+			p.Denoise = true
+		case "loudnorm", "normalize":
+			p.Normalize = true
+		case "all":
+			p = Preprocess{HighPass: true, Denoise: true, Normalize: true}
+		default:
+			return Preprocess{}, fmt.Errorf("unknown preprocessing step %q, expected %s or all", step, strings.Join(preprocessSteps, ", "))
+		}
+	}
+	return p, nil
+}
+
+func (p Preprocess) IsZero() bool { return p == Preprocess{} }
+
+// Key names the enabled steps in a fixed order, such as "highpass+loudnorm".
+// It is stored with summaries and used in object keys; the zero value is "".
+func (p Preprocess) Key() string {
+	var steps []string
+	for i, on := range []bool{p.HighPass, p.Denoise, p.Normalize} {
+		if on {
+			steps = append(steps, preprocessSteps[i])
+		}
+	}
+	return strings.Join(steps, "+")
+}
+
+func (p Preprocess) filters() string {
+	var f []string
+	if p.HighPass {
+		f = append(f, fmt.Sprintf("highpass=f=%d", HighPassHz))
+	}
+	if p.Denoise {
+		f = append(f, fmt.Sprintf("afftdn=nf=%d", DenoiseFloor))
+	}
+	if p.Normalize {
+		f = append(f, fmt.Sprintf("loudnorm=I=%s:TP=-1.5:LRA=11", strconv.FormatFloat(LoudnessTarget, 'f', -1, 64)))
+	}
+	return strings.Join(f, ",")
+}
+
+// PreprocessAudio applies p to inPath and writes a 16 kHz mono WAV to outPath.
+func PreprocessAudio(ctx context.Context, inPath, outPath string, p Preprocess) error {
+	args := []string{"-y", "-i", inPath, "-vn"}
+	if !p.IsZero() {
+		args = append(args, "-af", p.filters())
+	}
+	// loudnorm upsamples internally, so the rate is set again on the way out
+	args = append(args, "-acodec", "pcm_s16le", "-ar", "16000", "-ac", "1", outPath)
+
+	if out, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg preprocessing failed: %v\noutput: %s", err, out)
+	}
+	return nil
+}
+
+// Loudness is an EBU R128 measurement of a recording.
+type Loudness struct {
+	Integrated float64 `json:"integrated_lufs"`
+	Range      float64 `json:"range_lu"`
+	TruePeak   float64 `json:"true_peak_dbfs"`
+}
+
+var (
+	ebuIntegrated = regexp.MustCompile(`I:\s+(-?[0-9.]+|-inf) LUFS`)
+	ebuRange      = regexp.MustCompile(`LRA:\s+(-?[0-9.]+) LU`)
+	ebuPeak       = regexp.MustCompile(`Peak:\s+(-?[0-9.]+|-inf) dBFS`)
+)
+
+// MeasureLoudness runs ffmpeg's ebur128 filter over path.
+func MeasureLoudness(ctx context.Context, path string) (*Loudness, error) {
+	out, err := exec.CommandContext(ctx,
+		"ffmpeg",
+		"-nostats",
+		"-i", path,
+		"-af", "ebur128=peak=true",
+		"-f", "null",
+		"-",
+	).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg loudness measurement failed: %v\noutput: %s", err, out)
+	}
+	return parseEBUR128(string(out))
+}
+
+// parseEBUR128 reads the summary ebur128 prints at the end; the per-frame
+// lines before it carry running values with the same labels.
+func parseEBUR128(out string) (*Loudness, error) {
+	i := strings.LastIndex(out, "Summary:")
+	if i < 0 {
+		return nil, fmt.Errorf("no loudness summary in ffmpeg output")
+	}
+	summary := out[i:]
+
+	var l Loudness
+	for _, f := range []struct {
+		re  *regexp.Regexp
+		dst *float64
+	}{
+		{ebuIntegrated, &l.Integrated},
+		{ebuRange, &l.Range},
+		{ebuPeak, &l.TruePeak},
+	} {
+		m := f.re.FindStringSubmatch(summary)
+		if m == nil {
+			return nil, fmt.Errorf("incomplete loudness summary in ffmpeg output")
+		}
+		// silence measures as -inf, which JSON cannot hold; -70 is the
+		// absolute gate of EBU R128
+		if m[1] == "-inf" {
+			*f.dst = -70
+			continue
+		}
+		v, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		*f.dst = v
+	}
+	return &l, nil
+}
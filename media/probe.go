@@ -0,0 +1,93 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+// Info describes the audio stream of a media file.
+type Info struct {
+	Format     string  `json:"format"`
+	Codec      string  `json:"codec"`
+	Duration   float64 `json:"duration_seconds"`
+	SampleRate int     `json:"sample_rate"`
+	Channels   int     `json:"channels"`
+	Bitrate    int64   `json:"bitrate"`
+}
+
+var ErrUnknownFormat = errors.New("unknown media format")
+
+type prober func(r io.ReadSeeker, size int64) (*Info, error)
+
+// Probe sniffs the container from its magic bytes and parses the headers
+// natively. Only audio containers are understood; anything else returns
+// ErrUnknownFormat so callers can fall back to ffprobe.
+func Probe(r io.ReadSeeker, size int64) (*Info, error) {
+	head := make([]byte, 12)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	p := detect(head)
+	if p == nil {
+		return nil, ErrUnknownFormat
+	}
+
+	info, err := p(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if info.Bitrate == 0 && info.Duration > 0 {
+		info.Bitrate = int64(float64(size*8) / info.Duration)
+	}
+	return info, nil
+}
+
+func detect(head []byte) prober {
+	switch {
+	case len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WAVE")):
+		return probeWAV
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return probeFLAC
+	case bytes.HasPrefix(head, []byte("OggS")):
+		return probeOgg
+	case len(head) >= 8 && bytes.Equal(head[4:8], []byte("ftyp")):
+		return probeMP4
+	case bytes.HasPrefix(head, []byte("ID3")):
+		return probeMP3
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		return probeMP3
+	}
+	return nil
+}
+
+// ProbeFile probes a local file natively and falls back to ffprobe for
+// anything the native parsers reject, including video containers.
+func ProbeFile(ctx context.Context, path string) (*Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := Probe(f, st.Size())
+	if err == nil && info.Duration > 0 {
+		return info, nil
+	}
+
+	return FFProbe(ctx, path)
+}
@@ -0,0 +1,274 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+// The fixtures below are built byte by byte, so every header field a test
+// depends on is spelled out next to it.
+
+func le16(v uint16) []byte { return binary.LittleEndian.AppendUint16(nil, v) }
+func le32(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+func be32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+func join(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+// riffChunk pads odd-sized bodies the way RIFF requires.
+func riffChunk(id string, body []byte) []byte {
+	c := join([]byte(id), le32(uint32(len(body))), body)
+	if len(body)%2 == 1 {
+		c = append(c, 0)
+	}
+	return c
+}
+
+func wavFile(chunks ...[]byte) []byte {
+	body := join(chunks...)
+	return join([]byte("RIFF"), le32(uint32(4+len(body))), []byte("WAVE"), body)
+}
+
+// wavFmt is a fmt chunk body for PCM; ext is appended after the 16 bytes
+// every fmt chunk starts with, as WAVE_FORMAT_EXTENSIBLE files do.
+func wavFmt(format, channels uint16, rate uint32, bits uint16, ext []byte) []byte {
+	blockAlign := channels * bits / 8
+	return join(le16(format), le16(channels), le32(rate), le32(rate*uint32(blockAlign)), le16(blockAlign), le16(bits), ext)
+}
+
+// mp3Frames returns n MPEG-1 layer III frames at 128 kbps and 44.1 kHz,
+// each 417 bytes long.
+func mp3Frames(n int, mono bool) []byte {
+	hdr := []byte{0xFF, 0xFB, 0x90, 0x00}
+	if mono {
+		hdr[3] = 0xC0
+	}
+	frame := make([]byte, 417)
+	copy(frame, hdr)
+	return bytes.Repeat(frame, n)
+}
+
+func id3Tag(body int) []byte {
+	return join([]byte("ID3"), []byte{3, 0, 0}, []byte{0, 0, byte(body >> 7), byte(body & 0x7F)}, make([]byte, body))
+}
+
+func oggPage(headerType byte, granule uint64, packet []byte) []byte {
+	return join([]byte("OggS"), []byte{0, headerType},
+		binary.LittleEndian.AppendUint64(nil, granule),
+		le32(1), le32(0), le32(0),
+		[]byte{1, byte(len(packet))}, packet)
+}
+
+func opusHead(channels byte, preSkip uint16, rate uint32) []byte {
+	return join([]byte("OpusHead"), []byte{1, channels}, le16(preSkip), le32(rate), le16(0), []byte{0})
+}
+
+func vorbisID(channels byte, rate, nominal uint32) []byte {
+	return join([]byte{1}, []byte("vorbis"), le32(0), []byte{channels}, le32(rate),
+		le32(0), le32(nominal), le32(0), []byte{0xB8, 1})
+}
+
+func flacFile(blockType byte, rate uint32, channels, bits int, samples uint64) []byte {
+	si := make([]byte, 34)
+	packed := uint64(rate)<<44 | uint64(channels-1)<<41 | uint64(bits-1)<<36 | samples
+	binary.BigEndian.PutUint64(si[10:18], packed)
+	return join([]byte("fLaC"), []byte{0x80 | blockType, 0, 0, 34}, si)
+}
+
+func box(typ string, body ...[]byte) []byte {
+	b := join(body...)
+	return join(be32(uint32(8+len(b))), []byte(typ), b)
+}
+
+// mp4Header is the body of an mvhd or mdhd box, version 0, padded to the
+// 100 bytes of a real mvhd.
+func mp4Header(timescale, duration uint32) []byte {
+	b := join(make([]byte, 12), be32(timescale), be32(duration))
+	return append(b, make([]byte, 100-len(b))...)
+}
+
+func mp4AudioTrack(fourcc string, channels uint16, rate uint32) []byte {
+	hdlr := box("hdlr", make([]byte, 8), []byte("soun"), make([]byte, 13))
+	entry := box(fourcc, make([]byte, 16),
+		binary.BigEndian.AppendUint16(nil, channels), make([]byte, 6), be32(rate<<16))
+	stsd := box("stsd", make([]byte, 4), be32(1), entry)
+	return box("trak", box("mdia", hdlr, box("minf", box("stbl", stsd))))
+}
+
+func mp4File(moov ...[]byte) []byte {
+	return join(box("ftyp", []byte("isom"), be32(0x200), []byte("isomiso2mp41")), box("moov", moov...))
+}
+
+func TestProbe(t *testing.T) {
+	pcm := wavFmt(1, 1, 16000, 16, nil)
+	// WAVE_FORMAT_EXTENSIBLE: cbSize, valid bits, channel mask and the
+	// subformat GUID follow the first 16 bytes
+	extensible := wavFmt(0xFFFE, 2, 48000, 24, join(le16(22), le16(24), le32(3), make([]byte, 16)))
+
+	tests := []struct {
+		name string
+		data []byte
+		want Info
+	}{
+		{
+			name: "wav",
+			data: wavFile(riffChunk("fmt ", pcm), riffChunk("data", make([]byte, 32000))),
+			want: Info{Format: "wav", Codec: "pcm_s16le", Duration: 1, SampleRate: 16000, Channels: 1, Bitrate: 256000},
+		},
+		{
+			// the fmt chunk is longer than 16 bytes and an odd-sized chunk sits
+			// between it and the data
+			name: "wav extensible with list chunk",
+			data: wavFile(
+				riffChunk("fmt ", extensible),
+				riffChunk("LIST", []byte("INFOx")),
+				riffChunk("data", make([]byte, 2*288000)),
+			),
+			want: Info{Format: "wav", Codec: "pcm_s24le", Duration: 2, SampleRate: 48000, Channels: 2, Bitrate: 2304000},
+		},
+		{
+			name: "wav streamed without data size",
+			data: wavFile(riffChunk("fmt ", pcm), join([]byte("data"), le32(0xFFFFFFFF), make([]byte, 16000))),
+			want: Info{Format: "wav", Codec: "pcm_s16le", Duration: 0.5, SampleRate: 16000, Channels: 1, Bitrate: 256000},
+		},
+		{
+			name: "mp3",
+			data: mp3Frames(10, false),
+			want: Info{Format: "mp3", Codec: "mp3", Duration: 10 * 1152 / 44100.0, SampleRate: 44100, Channels: 2},
+		},
+		{
+			name: "mp3 with id3 tag",
+			data: join(id3Tag(100), mp3Frames(10, true)),
+			want: Info{Format: "mp3", Codec: "mp3", Duration: 10 * 1152 / 44100.0, SampleRate: 44100, Channels: 1},
+		},
+		{
+			name: "opus",
+			data: join(oggPage(2, 0, opusHead(2, 312, 48000)), oggPage(4, 2*48000+312, []byte{0})),
+			want: Info{Format: "ogg", Codec: "opus", Duration: 2, SampleRate: 48000, Channels: 2},
+		},
+		{
+			name: "vorbis",
+			data: join(oggPage(2, 0, vorbisID(1, 44100, 96000)), oggPage(4, 3*44100, []byte{0})),
+			want: Info{Format: "ogg", Codec: "vorbis", Duration: 3, SampleRate: 44100, Channels: 1, Bitrate: 96000},
+		},
+		{
+			name: "flac",
+			data: flacFile(0, 44100, 2, 16, 441000),
+			want: Info{Format: "flac", Codec: "flac", Duration: 10, SampleRate: 44100, Channels: 2},
+		},
+		{
+			name: "m4a",
+			data: mp4File(box("mvhd", mp4Header(1000, 5000)), mp4AudioTrack("mp4a", 2, 44100)),
+			want: Info{Format: "mp4", Codec: "aac", Duration: 5, SampleRate: 44100, Channels: 2},
+		},
+		{
+			name: "m4a duration from mdhd",
+			data: mp4File(box("trak", box("mdia",
+				box("mdhd", mp4Header(44100, 44100*4)),
+				box("hdlr", make([]byte, 8), []byte("soun"), make([]byte, 13)),
+			))),
+			want: Info{Format: "mp4", Duration: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Probe(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err != nil {
+				t.Fatalf("Probe: %v", err)
+			}
+			if got.Format != tt.want.Format || got.Codec != tt.want.Codec ||
+				got.SampleRate != tt.want.SampleRate || got.Channels != tt.want.Channels {
+				t.Errorf("Probe = %+v, want %+v", *got, tt.want)
+			}
+			if math.Abs(got.Duration-tt.want.Duration) > 1e-6 {
+				t.Errorf("Duration = %v, want %v", got.Duration, tt.want.Duration)
+			}
+			if tt.want.Bitrate != 0 && got.Bitrate != tt.want.Bitrate {
+				t.Errorf("Bitrate = %d, want %d", got.Bitrate, tt.want.Bitrate)
+			}
+		})
+	}
+}
+
+func TestProbeRejects(t *testing.T) {
+	pcm := wavFmt(1, 1, 16000, 16, nil)
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, "unknown media format"},
+		{"garbage", []byte("this is not an audio file at all"), "unknown media format"},
+		{
+			// a fmt chunk that claims 2 GB must not be allocated
+			"wav fmt past end",
+			wavFile(join([]byte("fmt "), le32(0x7FFFFFF0), pcm), riffChunk("data", make([]byte, 64))),
+			"runs past the end",
+		},
+		{"wav fmt too short", wavFile(riffChunk("fmt ", pcm[:14]), riffChunk("data", make([]byte, 64))), "too short"},
+		{"wav data before fmt", wavFile(riffChunk("data", make([]byte, 64)), riffChunk("fmt ", pcm)), "before fmt"},
+		{"wav without data", wavFile(riffChunk("fmt ", pcm)), "missing data"},
+		{"wav chunk size past end", wavFile(riffChunk("fmt ", pcm), join([]byte("junk"), le32(0xFFFFFFF0))), "missing data"},
+		{"wav zero byte rate", wavFile(riffChunk("fmt ", wavFmt(1, 0, 16000, 16, nil)), riffChunk("data", make([]byte, 64))), "before fmt"},
+		{"mp3 tag past end", join(id3Tag(0)[:6], []byte{0x7F, 0x7F, 0x7F, 0x7F}), "truncated ID3"},
+		{"mp3 without frames", join(id3Tag(10), make([]byte, 70*1024)), "no frame sync"},
+		{"ogg truncated", []byte("OggS\x00\x02"), "truncated page"},
+		{"ogg unknown codec", oggPage(2, 0, []byte("Speex   and more bytes")), "unsupported codec"},
+		{"ogg without granule", oggPage(2, ^uint64(0), opusHead(2, 0, 48000)), "no granule"},
+		{"flac truncated", flacFile(0, 44100, 2, 16, 1)[:20], "read streaminfo"},
+		{"flac without streaminfo", flacFile(4, 44100, 2, 16, 1), "not streaminfo"},
+		{"flac zero sample rate", flacFile(0, 0, 2, 16, 1), "invalid sample rate"},
+		{"mp4 without moov", box("ftyp", []byte("isom")), "missing moov"},
+		{"mp4 box past end", join(box("ftyp", []byte("isom")), be32(1<<20), []byte("moov")), "missing moov"},
+		{"mp4 without duration", mp4File(mp4AudioTrack("mp4a", 2, 44100)), "no duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Probe(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err == nil {
+				t.Fatalf("Probe = %+v, want an error", *info)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// probeSeeds are valid files of every format, used to check that no prefix
+// of them makes a parser panic.
+func probeSeeds() [][]byte {
+	return [][]byte{
+		wavFile(riffChunk("fmt ", wavFmt(1, 1, 16000, 16, nil)), riffChunk("data", make([]byte, 320))),
+		join(id3Tag(20), mp3Frames(3, false)),
+		join(oggPage(2, 0, opusHead(2, 312, 48000)), oggPage(4, 48312, []byte{0})),
+		flacFile(0, 44100, 2, 16, 441000),
+		mp4File(box("mvhd", mp4Header(1000, 5000)), mp4AudioTrack("mp4a", 2, 44100)),
+	}
+}
+
+func TestProbeTruncated(t *testing.T) {
+	for _, data := range probeSeeds() {
+		for n := 0; n < len(data); n++ {
+			// any result is fine as long as the parser returns
+			Probe(bytes.NewReader(data[:n]), int64(n))
+		}
+	}
+}
+
+func FuzzProbe(f *testing.F) {
+	for _, data := range probeSeeds() {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := Probe(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		if info.Duration < 0 || math.IsNaN(info.Duration) || info.SampleRate < 0 || info.Channels < 0 {
+			t.Fatalf("Probe = %+v", *info)
+		}
+	})
+}
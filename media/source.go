@@ -0,0 +1,148 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnsupportedSource = errors.New("link is not supported by any media source")
+	ErrNoCaptions        = errors.New("no captions available")
+)
+
+// Source is a remote video or audio item. Provider and ExternalID identify it
+// across differently shaped links; URL is the canonical link that is stored
+// and handed to downloaders in place of the submitted one.
+type Source struct {
+	Provider   string
+	ExternalID string
+	URL        string
+	// Start is the start time the submitted link pointed at, if any. It is
+	// the default start of Range.
+	Start time.Duration
+	// Range limits FetchAudio to part of the item; it is set by the caller,
+	// never parsed from the link.
+	Range TimeRange
+}
+
+// SourceMetadata is what a provider knows about an item before downloading
+// it. Fields the provider cannot tell are left empty. It is stored as JSON
+// with the media source, so field names must stay stable.
+type SourceMetadata struct {
+	Title    string `json:"title,omitempty"`
+	Uploader string `json:"uploader,omitempty"`
+	Channel  string `json:"channel,omitempty"`
+	// UploadDate is formatted as YYYY-MM-DD.
+	UploadDate   string    `json:"upload_date,omitempty"`
+	Duration     float64   `json:"duration_seconds,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	Tags         []string  `json:"tags,omitempty"`
+	Chapters     []Chapter `json:"chapters,omitempty"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+}
+
+// Chapter is a creator-provided chapter; times are in seconds.
+type Chapter struct {
+	Start float64 `json:"start_seconds"`
+	End   float64 `json:"end_seconds,omitempty"`
+	Title string  `json:"title"`
+}
+
+// maxPromptDescription caps how much of a description goes into the prompt;
+// long ones are mostly links and sponsor copy.
+const maxPromptDescription = 3000
+
+// ChaptersIn returns the chapters that overlap r.
+func (m *SourceMetadata) ChaptersIn(r TimeRange) []Chapter {
+	var out []Chapter
+	for _, c := range m.Chapters {
+		if c.End != 0 && c.End <= r.Start.Seconds() {
+			continue
+		}
+		if r.End != 0 && c.Start >= r.End.Seconds() {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// PromptContext renders the description and the chapters overlapping r the
+// way they are given to the summarizer, or "" when there are neither.
+func (m *SourceMetadata) PromptContext(r TimeRange) string {
+	if m == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	if desc := strings.TrimSpace(m.Description); desc != "" {
+		if runes := []rune(desc); len(runes) > maxPromptDescription {
+			desc = string(runes[:maxPromptDescription]) + "…"
+		}
+		sb.WriteString("Description from the creator:\n")
+		sb.WriteString(desc)
+		sb.WriteString("\n\n")
+	}
+	if chapters := m.ChaptersIn(r); len(chapters) > 0 {
+		sb.WriteString("Chapters:\n")
+		for _, c := range chapters {
+			fmt.Fprintf(&sb, "%s %s\n", clock(time.Duration(c.Start*float64(time.Second))), c.Title)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// SourceProvider resolves links for one platform and fetches what the audio
+// pipeline needs from it.
+type SourceProvider interface {
+	// Name is stored as media_sources.provider; it must never change.
+	Name() string
+	// Validate reports whether link belongs to this provider.
+	Validate(link string) error
+	// CanonicalID resolves a valid link into the provider's ID for the item.
+	CanonicalID(link string) (*Source, error)
+	Metadata(ctx context.Context, src *Source) (*SourceMetadata, error)
+	// FetchAudio downloads the audio of src into dir and returns the path of
+	// the file; the format is whatever the platform serves. Only src.Range is
+	// returned when it is set.
+	FetchAudio(ctx context.Context, src *Source, dir string) (string, error)
+	// FetchCaptions returns the plain text of the item's captions, or
+	// ErrNoCaptions.
+	FetchCaptions(ctx context.Context, src *Source) (string, error)
+}
+
+// Providers are tried in order by ResolveSource, so the generic yt-dlp
+// provider, which accepts any web link, has to stay last.
+var Providers = []SourceProvider{
+	YouTube{},
+	Vimeo{},
+	DirectMedia{},
+	YTDLP{},
+}
+
+// ResolveSource picks the first provider that accepts link and resolves it.
+// When providers are given only those are tried, otherwise all of Providers.
+func ResolveSource(link string, providers ...SourceProvider) (SourceProvider, *Source, error) {
+	if len(providers) == 0 {
+		providers = Providers
+	}
+	for _, p := range providers {
+		if p.Validate(link) != nil {
+			continue
+		}
+		src, err := p.CanonicalID(link)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", p.Name(), err)
+		}
+		return p, src, nil
+	}
+	if len(providers) == 1 {
+		// a single provider was asked for, so its reason is the useful one
+		return nil, nil, providers[0].Validate(link)
+	}
+	return nil, nil, ErrUnsupportedSource
+}
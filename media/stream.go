@@ -0,0 +1,130 @@
+package media
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+)
+
+// StreamFormat describes the frames of a live audio stream. PCM is signed
+// 16-bit little-endian at SampleRate with Channels interleaved; Opus is
+// expected in an Ogg or WebM container, as browsers' MediaRecorder sends it.
+type StreamFormat struct {
+	Codec      string
+	SampleRate int
+	Channels   int
+}
+
+const (
+	CodecPCM  = "pcm"
+	CodecOpus = "opus"
+)
+
+func (f StreamFormat) Validate() error {
+	switch f.Codec {
+	case CodecOpus:
+		return nil
+	case CodecPCM:
+		if f.SampleRate < 8000 || f.SampleRate > 192000 {
+			return errors.New("sample rate must be between 8000 and 192000")
+		}
+		if f.Channels < 1 || f.Channels > 8 {
+			return errors.New("channels must be between 1 and 8")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported codec %q, expected %s or %s", f.Codec, CodecPCM, CodecOpus)
+}
+
+// streamRate is the rate whisper expects, which every stream is converted to.
+const streamRate = 16000
+
+// AudioStream turns audio frames written to it into 16 kHz mono samples.
+// 16 kHz mono PCM is passed straight through; anything else is converted by
+// an ffmpeg process reading from a pipe.
+type AudioStream struct {
+	in  io.WriteCloser
+	out io.Reader
+	cmd *exec.Cmd
+
+	buf, carry []byte
+}
+
+func NewAudioStream(ctx context.Context, f StreamFormat) (*AudioStream, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	if f.Codec == CodecPCM && f.SampleRate == streamRate && f.Channels == 1 {
+		r, w := io.Pipe()
+		return &AudioStream{in: w, out: r}, nil
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error", "-fflags", "nobuffer"}
+	if f.Codec == CodecPCM {
+		args = append(args, "-f", "s16le", "-ar", strconv.Itoa(f.SampleRate), "-ac", strconv.Itoa(f.Channels))
+	}
+	args = append(args, "-i", "pipe:0", "-f", "s16le", "-ar", strconv.Itoa(streamRate), "-ac", "1", "pipe:1")
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+	return &AudioStream{in: in, out: out, cmd: cmd}, nil
+}
+
+// Write feeds one frame of the stream's format.
+func (a *AudioStream) Write(frame []byte) (int, error) {
+	return a.in.Write(frame)
+}
+
+// ReadSamples blocks until converted audio is available and returns it as
+// float32 samples in [-1, 1]. It returns io.EOF once the stream is closed and
+// drained.
+func (a *AudioStream) ReadSamples() ([]float32, error) {
+	if a.buf == nil {
+		a.buf = make([]byte, 32*1024)
+	}
+	// an odd byte left from the last read is the first half of a sample
+	c := copy(a.buf, a.carry)
+	a.carry = a.carry[:0]
+	n, err := a.out.Read(a.buf[c:])
+	n += c
+	if n%2 == 1 {
+		a.carry = append(a.carry, a.buf[n-1])
+		n--
+	}
+	samples := make([]float32, n/2)
+	for i := range samples {
+		samples[i] = float32(int16(binary.LittleEndian.Uint16(a.buf[2*i:]))) / math.MaxInt16
+	}
+	if n > 0 {
+		return samples, nil
+	}
+	return nil, err
+}
+
+// Close ends the input; samples still being converted can be read until
+// ReadSamples returns io.EOF. Wait reports how the conversion ended.
+func (a *AudioStream) Close() error {
+	return a.in.Close()
+}
+
+func (a *AudioStream) Wait() error {
+	if a.cmd == nil {
+		return nil
+	}
+	return a.cmd.Wait()
+}
@@ -0,0 +1,94 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// TimeRange selects part of a recording. A zero End runs to the end of it;
+// the zero TimeRange is the whole recording.
+type TimeRange struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+func (r TimeRange) IsZero() bool { return r.Start == 0 && r.End == 0 }
+
+func (r TimeRange) Validate() error {
+	if r.Start < 0 || r.End < 0 {
+		return errors.New("time range cannot be negative")
+	}
+	if r.End != 0 && r.End <= r.Start {
+		return errors.New("time range end must be after its start")
+	}
+	return nil
+}
+
+// Key identifies the range in object keys, in milliseconds.
+func (r TimeRange) Key() string {
+	end := "end"
+	if r.End != 0 {
+		end = strconv.FormatInt(r.End.Milliseconds(), 10)
+	}
+	return fmt.Sprintf("%d-%s", r.Start.Milliseconds(), end)
+}
+
+// String renders the range the way it is described to the summarizer.
+func (r TimeRange) String() string {
+	if r.End == 0 {
+		return "from " + clock(r.Start) + " to the end"
+	}
+	return "from " + clock(r.Start) + " to " + clock(r.End)
+}
+
+func clock(d time.Duration) string {
+	s := int(d.Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// ytdlpSection is the --download-sections argument for the range.
+func (r TimeRange) ytdlpSection() string {
+	end := "inf"
+	if r.End != 0 {
+		end = strconv.FormatFloat(r.End.Seconds(), 'f', -1, 64)
+	}
+	return "*" + strconv.FormatFloat(r.Start.Seconds(), 'f', -1, 64) + "-" + end
+}
+
+// TrimAudio cuts r out of inPath into a 16 kHz mono WAV at outPath.
+func TrimAudio(ctx context.Context, inPath, outPath string, r TimeRange) error {
+	args := []string{"-y", "-ss", strconv.FormatFloat(r.Start.Seconds(), 'f', -1, 64), "-i", inPath}
+	if r.End != 0 {
+		// -ss before -i resets timestamps, so the end is given as a length
+		args = append(args, "-t", strconv.FormatFloat((r.End-r.Start).Seconds(), 'f', -1, 64))
+	}
+	args = append(args, "-vn", "-acodec", "pcm_s16le", "-ar", "16000", "-ac", "1", outPath)
+
+	if out, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg trim failed: %v\noutput: %s", err, out)
+	}
+	return nil
+}
+
+// trimVideo cuts r out of a video without re-encoding it. The cut starts at
+// the keyframe before r.Start, so stills taken from it can be a few seconds
+// early; that is close enough for a timeline.
+func trimVideo(ctx context.Context, inPath, outPath string, r TimeRange) error {
+	args := []string{"-y", "-ss", strconv.FormatFloat(r.Start.Seconds(), 'f', -1, 64), "-i", inPath}
+	if r.End != 0 {
+		args = append(args, "-t", strconv.FormatFloat((r.End-r.Start).Seconds(), 'f', -1, 64))
+	}
+	args = append(args, "-an", "-c:v", "copy", outPath)
+
+	if out, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg trim failed: %v\noutput: %s", err, out)
+	}
+	return nil
+}
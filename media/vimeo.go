@@ -0,0 +1,82 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	vimeoIDRegex   = regexp.MustCompile(`^[0-9]{6,12}$`)
+	vimeoHashRegex = regexp.MustCompile(`^[0-9a-f]{8,20}$`)
+)
+
+var vimeoHosts = map[string]bool{
+	"vimeo.com":        true,
+	"www.vimeo.com":    true,
+	"player.vimeo.com": true,
+}
+
+// Vimeo handles vimeo.com/ID, player.vimeo.com/video/ID and the channel,
+// group and showcase paths that end in a video ID. Unlisted videos keep their
+// privacy hash in the canonical URL, since they cannot be fetched without it.
+type Vimeo struct{}
+
+func (Vimeo) Name() string { return "vimeo" }
+
+func (v Vimeo) Validate(link string) error {
+	_, err := v.CanonicalID(link)
+	return err
+}
+
+func (Vimeo) CanonicalID(link string) (*Source, error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return nil, errors.New("invalid url")
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || !vimeoHosts[strings.ToLower(u.Hostname())] {
+		return nil, errors.New("not a vimeo link")
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	id, hash := "", u.Query().Get("h")
+	for i, p := range parts {
+		if vimeoIDRegex.MatchString(p) {
+			id = p
+			if i+1 < len(parts) && vimeoHashRegex.MatchString(parts[i+1]) {
+				hash = parts[i+1]
+			}
+			break
+		}
+	}
+	if id == "" {
+		return nil, errors.New("invalid vimeo video id")
+	}
+
+	canonical := "https://vimeo.com/" + id
+	if hash != "" {
+		if !vimeoHashRegex.MatchString(hash) {
+			return nil, errors.New("invalid vimeo privacy hash")
+		}
+		canonical += "/" + hash
+	}
+	return &Source{Provider: "vimeo", ExternalID: id, URL: canonical}, nil
+}
+
+func (Vimeo) Metadata(ctx context.Context, src *Source) (*SourceMetadata, error) {
+	return ytdlpMetadata(ctx, src.URL)
+}
+
+func (Vimeo) FetchAudio(ctx context.Context, src *Source, dir string) (string, error) {
+	return ytdlpAudio(ctx, src.URL, dir, src.Range)
+}
+
+func (Vimeo) FetchVideo(ctx context.Context, src *Source, dir string) (string, error) {
+	return ytdlpVideo(ctx, src.URL, dir, src.Range)
+}
+
+func (Vimeo) FetchCaptions(ctx context.Context, src *Source) (string, error) {
+	return ytdlpCaptions(ctx, src.URL)
+}
@@ -0,0 +1,51 @@
+package media
+
+import (
+	"regexp"
+	"strings"
+)
+
+var vttTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+var vttEntities = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&nbsp;", " ")
+
+// VTTText flattens WebVTT captions into plain text. Automatic captions repeat
+// each line while the next one scrolls in, so consecutive duplicates are
+// dropped.
+func VTTText(data []byte) string {
+	raw := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	var (
+		lines []string
+		last  string
+		skip  bool
+	)
+	for i, line := range raw {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			skip = false
+			continue
+		case skip:
+			continue
+		case strings.HasPrefix(line, "WEBVTT"), strings.HasPrefix(line, "NOTE"),
+			strings.HasPrefix(line, "STYLE"), strings.HasPrefix(line, "REGION"):
+			// header and metadata blocks run until the next blank line
+			skip = true
+			continue
+		case strings.Contains(line, "-->"):
+			continue
+		case i+1 < len(raw) && strings.Contains(raw[i+1], "-->"):
+			// cue identifier
+			continue
+		}
+
+		line = strings.TrimSpace(vttEntities.Replace(vttTagRegex.ReplaceAllString(line, "")))
+		if line == "" || line == last {
+			continue
+		}
+		lines = append(lines, line)
+		last = line
+	}
+	return strings.Join(lines, " ")
+}
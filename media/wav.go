@@ -0,0 +1,98 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+func probeWAV(r io.ReadSeeker, size int64) (*Info, error) {
+	if _, err := r.Seek(12, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var (
+		format     uint16
+		channels   uint16
+		sampleRate uint32
+		byteRate   uint32
+		bits       uint16
+		haveFmt    bool
+	)
+
+	hdr := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			return nil, errors.New("wav: missing data chunk")
+		}
+		id := string(hdr[:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(hdr[4:]))
+
+		switch id {
+		case "fmt ":
+			if chunkSize < 16 {
+				return nil, errors.New("wav: fmt chunk too short")
+			}
+			pos, _ := r.Seek(0, io.SeekCurrent)
+			if pos+chunkSize > size {
+				return nil, errors.New("wav: fmt chunk runs past the end of the file")
+			}
+			// only the first 16 bytes are read; extensions after them are skipped
+			buf := make([]byte, 16)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return nil, fmt.Errorf("wav: read fmt chunk: %w", err)
+			}
+			if _, err := io.CopyN(io.Discard, r, chunkSize-16); err != nil {
+				return nil, fmt.Errorf("wav: read fmt chunk: %w", err)
+			}
+			format = binary.LittleEndian.Uint16(buf[0:])
+			channels = binary.LittleEndian.Uint16(buf[2:])
+			sampleRate = binary.LittleEndian.Uint32(buf[4:])
+			byteRate = binary.LittleEndian.Uint32(buf[8:])
+			bits = binary.LittleEndian.Uint16(buf[14:])
+			haveFmt = true
+			if chunkSize%2 == 1 {
+				r.Seek(1, io.SeekCurrent)
+			}
+		case "data":
+			if !haveFmt || byteRate == 0 {
+				return nil, errors.New("wav: data chunk before fmt chunk")
+			}
+			pos, _ := r.Seek(0, io.SeekCurrent)
+			// streamed WAVs often leave the size as 0 or 0xFFFFFFFF
+			if chunkSize == 0 || chunkSize == 0xFFFFFFFF || pos+chunkSize > size {
+				chunkSize = size - pos
+			}
+			return &Info{
+				Format:     "wav",
+				Codec:      wavCodec(format, bits),
+				Duration:   float64(chunkSize) / float64(byteRate),
+				SampleRate: int(sampleRate),
+				Channels:   int(channels),
+				Bitrate:    int64(byteRate) * 8,
+			}, nil
+		default:
+			if _, err := r.Seek(chunkSize+chunkSize%2, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func wavCodec(format, bits uint16) string {
+	switch format {
+	case 1, 0xFFFE:
+		if bits == 8 {
+			return "pcm_u8"
+		}
+		return fmt.Sprintf("pcm_s%dle", bits)
+	case 3:
+		return fmt.Sprintf("pcm_f%dle", bits)
+	case 6:
+		return "pcm_alaw"
+	case 7:
+		return "pcm_mulaw"
+	}
+	return fmt.Sprintf("wav_0x%04x", format)
+}
@@ -0,0 +1,42 @@
+package media
+
+import (
+	"context"
+
+	"github.com/lupppig/briefly/utils"
+)
+
+// YouTube handles single video links in every form utils.ParseYouTubeURL
+// accepts. Playlists and channels are expanded before they get here.
+type YouTube struct{}
+
+func (YouTube) Name() string { return "youtube" }
+
+func (YouTube) Validate(link string) error {
+	_, err := utils.ParseYouTubeURL(link)
+	return err
+}
+
+func (YouTube) CanonicalID(link string) (*Source, error) {
+	v, err := utils.ParseYouTubeURL(link)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{Provider: "youtube", ExternalID: v.ID, URL: v.URL, Start: v.Start}, nil
+}
+
+func (YouTube) Metadata(ctx context.Context, src *Source) (*SourceMetadata, error) {
+	return ytdlpMetadata(ctx, src.URL)
+}
+
+func (YouTube) FetchAudio(ctx context.Context, src *Source, dir string) (string, error) {
+	return ytdlpAudio(ctx, src.URL, dir, src.Range)
+}
+
+func (YouTube) FetchVideo(ctx context.Context, src *Source, dir string) (string, error) {
+	return ytdlpVideo(ctx, src.URL, dir, src.Range)
+}
+
+func (YouTube) FetchCaptions(ctx context.Context, src *Source) (string, error) {
+	return ytdlpCaptions(ctx, src.URL)
+}
@@ -0,0 +1,193 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/lupppig/briefly/outbound"
+	"github.com/lupppig/briefly/utils"
+)
+
+// YTDLP handles any web page yt-dlp has an extractor for. It accepts every
+// http(s) link, so it is the last provider tried.
+type YTDLP struct{}
+
+func (YTDLP) Name() string { return "ytdlp" }
+
+func (YTDLP) Validate(link string) error {
+	_, err := utils.NormalizeURL(link)
+	return err
+}
+
+// CanonicalID keys generic links by their normalized URL, which is the only
+// stable ID available without asking yt-dlp first.
+func (YTDLP) CanonicalID(link string) (*Source, error) {
+	u, err := utils.NormalizeURL(link)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{Provider: "ytdlp", ExternalID: u, URL: u}, nil
+}
+
+func (YTDLP) Metadata(ctx context.Context, src *Source) (*SourceMetadata, error) {
+	return ytdlpMetadata(ctx, src.URL)
+}
+
+func (YTDLP) FetchAudio(ctx context.Context, src *Source, dir string) (string, error) {
+	return ytdlpAudio(ctx, src.URL, dir, src.Range)
+}
+
+func (YTDLP) FetchVideo(ctx context.Context, src *Source, dir string) (string, error) {
+	return ytdlpVideo(ctx, src.URL, dir, src.Range)
+}
+
+func (YTDLP) FetchCaptions(ctx context.Context, src *Source) (string, error) {
+	return ytdlpCaptions(ctx, src.URL)
+}
+
+// runYTDLP runs yt-dlp on a single video; see RunYTDLP.
+func runYTDLP(ctx context.Context, link string, args ...string) ([]byte, error) {
+	return RunYTDLP(ctx, link, append(args, "--no-playlist")...)
+}
+
+// RunYTDLP runs yt-dlp with args followed by link and returns its output.
+// yt-dlp opens its own connections, so it is pointed at an outbound proxy
+// that holds every request it makes, redirects included, to the outbound
+// policy.
+func RunYTDLP(ctx context.Context, link string, args ...string) ([]byte, error) {
+	if err := outbound.Validate(ctx, link); err != nil {
+		return nil, err
+	}
+	proxy, err := outbound.Default.StartProxy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start outbound proxy: %w", err)
+	}
+	defer proxy.Close()
+
+	args = append(args, "--proxy", proxy.URL(), "--no-warnings", "--", link)
+	cmd := exec.CommandContext(ctx, "yt-dlp", args...)
+	out, err := cmd.Output()
+	if err != nil {
+		var stderr []byte
+		if ee, ok := err.(*exec.ExitError); ok {
+			stderr = ee.Stderr
+		}
+		return nil, fmt.Errorf("yt-dlp failed: %v\noutput: %s", err, stderr)
+	}
+	return out, nil
+}
+
+func ytdlpMetadata(ctx context.Context, link string) (*SourceMetadata, error) {
+	out, err := runYTDLP(ctx, link, "--skip-download", "--dump-single-json")
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Title       string   `json:"title"`
+		Uploader    string   `json:"uploader"`
+		Channel     string   `json:"channel"`
+		UploadDate  string   `json:"upload_date"`
+		Duration    float64  `json:"duration"`
+		Description string   `json:"description"`
+		Tags        []string `json:"tags"`
+		Thumbnail   string   `json:"thumbnail"`
+		Chapters    []struct {
+			StartTime float64 `json:"start_time"`
+			EndTime   float64 `json:"end_time"`
+			Title     string  `json:"title"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse yt-dlp metadata: %w", err)
+	}
+
+	meta := &SourceMetadata{
+		Title:        info.Title,
+		Uploader:     info.Uploader,
+		Channel:      info.Channel,
+		Duration:     info.Duration,
+		Description:  info.Description,
+		Tags:         info.Tags,
+		ThumbnailURL: info.Thumbnail,
+	}
+	if meta.Uploader == "" {
+		meta.Uploader = info.Channel
+	}
+	// yt-dlp reports dates as YYYYMMDD
+	if d, err := time.Parse("20060102", info.UploadDate); err == nil {
+		meta.UploadDate = d.Format(time.DateOnly)
+	}
+	for _, c := range info.Chapters {
+		meta.Chapters = append(meta.Chapters, Chapter{Start: c.StartTime, End: c.EndTime, Title: c.Title})
+	}
+	return meta, nil
+}
+
+// ytdlpAudio downloads the best audio stream; for a range only that section
+// is downloaded.
+func ytdlpAudio(ctx context.Context, link, dir string, r TimeRange) (string, error) {
+	args := []string{"-f", "bestaudio/best", "-o", filepath.Join(dir, "source.%(ext)s")}
+	if !r.IsZero() {
+		args = append(args, "--download-sections", r.ytdlpSection())
+	}
+	if _, err := runYTDLP(ctx, link, args...); err != nil {
+		return "", err
+	}
+	return firstMatch(filepath.Join(dir, "source.*"))
+}
+
+// ytdlpCaptions downloads English captions as WebVTT, preferring uploaded
+// captions over automatic ones, and flattens them into plain text.
+func ytdlpCaptions(ctx context.Context, link string) (string, error) {
+	dir, err := os.MkdirTemp("", "captions-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := runYTDLP(ctx, link,
+		"--skip-download",
+		"--write-subs",
+		"--write-auto-subs",
+		"--sub-langs", "en.*,en",
+		"--sub-format", "vtt",
+		"-o", filepath.Join(dir, "captions"),
+	); err != nil {
+		return "", err
+	}
+
+	path, err := firstMatch(filepath.Join(dir, "captions*.vtt"))
+	if err != nil {
+		return "", ErrNoCaptions
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	text := VTTText(data)
+	if text == "" {
+		return "", ErrNoCaptions
+	}
+	return text, nil
+}
+
+func firstMatch(pattern string) (string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", errors.New("yt-dlp did not write any file")
+	}
+	sort.Strings(matches)
+	return matches[0], nil
+}
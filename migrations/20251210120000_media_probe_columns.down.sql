@@ -0,0 +1,5 @@
+ALTER TABLE uploaded_files
+    DROP COLUMN sample_rate,
+    DROP COLUMN channels,
+    DROP COLUMN codec,
+    DROP COLUMN bitrate;
@@ -0,0 +1,5 @@
+ALTER TABLE uploaded_files
+    ADD COLUMN sample_rate INT,
+    ADD COLUMN channels INT,
+    ADD COLUMN codec VARCHAR(50),
+    ADD COLUMN bitrate BIGINT;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_contents_parent_id;
+
+ALTER TABLE contents
+    DROP COLUMN parent_id,
+    DROP COLUMN title,
+    DROP COLUMN position;
@@ -0,0 +1,7 @@
+ALTER TABLE contents
+    ADD COLUMN parent_id UUID REFERENCES contents(id) ON DELETE CASCADE,
+    ADD COLUMN title TEXT,
+    ADD COLUMN position INT;
+
+CREATE INDEX idx_contents_parent_id
+ON contents (parent_id, position);
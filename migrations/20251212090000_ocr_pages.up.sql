@@ -0,0 +1,7 @@
+CREATE TABLE ocr_pages (
+    file_id UUID NOT NULL REFERENCES uploaded_files(id) ON DELETE CASCADE,
+    page_number INT NOT NULL,
+    confidence DOUBLE PRECISION NOT NULL,
+    low_quality BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (file_id, page_number)
+);
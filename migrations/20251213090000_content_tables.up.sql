@@ -0,0 +1,9 @@
+CREATE TABLE content_tables (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    file_id UUID NOT NULL REFERENCES uploaded_files(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    title TEXT,
+    page INT,
+    rows JSONB NOT NULL,
+    UNIQUE (file_id, position)
+);
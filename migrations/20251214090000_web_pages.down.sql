@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_contents_web_page_id;
+
+ALTER TABLE contents
+    DROP COLUMN web_page_id;
+
+DROP TABLE IF EXISTS web_pages;
@@ -0,0 +1,17 @@
+CREATE TABLE web_pages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    url TEXT NOT NULL UNIQUE,
+    final_url TEXT,
+    etag TEXT,
+    content_hash TEXT,
+    mime_type VARCHAR(100),
+    title TEXT,
+    fetched_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+ALTER TABLE contents
+    ADD COLUMN web_page_id UUID REFERENCES web_pages(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_contents_web_page_id
+ON contents (web_page_id);
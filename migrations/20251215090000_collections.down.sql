@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_contents_collection_id;
+
+ALTER TABLE contents
+    DROP COLUMN collection_id;
+
+DROP TABLE IF EXISTS collection_items;
+DROP TABLE IF EXISTS collections;
@@ -0,0 +1,20 @@
+CREATE TABLE collections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind VARCHAR(20) NOT NULL,
+    source TEXT NOT NULL,
+    title TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE collection_items (
+    collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    content_id UUID NOT NULL REFERENCES contents(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    PRIMARY KEY (collection_id, content_id)
+);
+
+ALTER TABLE contents
+    ADD COLUMN collection_id UUID REFERENCES collections(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_contents_collection_id
+ON contents (collection_id);
@@ -0,0 +1,2 @@
+-- the original links are not kept, canonical ones are left in place
+SELECT 1;
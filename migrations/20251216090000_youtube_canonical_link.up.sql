@@ -0,0 +1,2 @@
+UPDATE youtube
+SET link = 'https://www.youtube.com/watch?v=' || video_id;
@@ -0,0 +1,26 @@
+DROP INDEX IF EXISTS idx_contents_source_id;
+
+ALTER TABLE contents RENAME COLUMN source_id TO youtube_id;
+
+-- rows from other providers have no place in the youtube table
+DELETE FROM contents
+WHERE youtube_id IN (SELECT id FROM media_sources WHERE provider <> 'youtube');
+
+DELETE FROM media_sources
+WHERE provider <> 'youtube';
+
+ALTER TABLE media_sources
+    DROP CONSTRAINT media_sources_provider_external_id_key;
+
+ALTER TABLE media_sources
+    DROP COLUMN provider;
+
+ALTER TABLE media_sources RENAME COLUMN external_id TO video_id;
+
+ALTER TABLE media_sources
+    ADD CONSTRAINT youtube_video_id_key UNIQUE (video_id);
+
+CREATE INDEX idx_video_id
+ON media_sources (video_id);
+
+ALTER TABLE media_sources RENAME TO youtube;
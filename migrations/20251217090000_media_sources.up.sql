@@ -0,0 +1,22 @@
+ALTER TABLE youtube RENAME TO media_sources;
+
+ALTER TABLE media_sources RENAME COLUMN video_id TO external_id;
+
+ALTER TABLE media_sources
+    ADD COLUMN provider VARCHAR(30) NOT NULL DEFAULT 'youtube';
+
+ALTER TABLE media_sources
+    ALTER COLUMN provider DROP DEFAULT;
+
+ALTER TABLE media_sources
+    DROP CONSTRAINT youtube_video_id_key;
+
+DROP INDEX IF EXISTS idx_video_id;
+
+ALTER TABLE media_sources
+    ADD CONSTRAINT media_sources_provider_external_id_key UNIQUE (provider, external_id);
+
+ALTER TABLE contents RENAME COLUMN youtube_id TO source_id;
+
+CREATE INDEX idx_contents_source_id
+ON contents (source_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS transcript_segments;
+
+ALTER TABLE contents
+    DROP COLUMN range_start,
+    DROP COLUMN range_end;
@@ -0,0 +1,12 @@
+ALTER TABLE contents
+    ADD COLUMN range_start DOUBLE PRECISION,
+    ADD COLUMN range_end DOUBLE PRECISION;
+
+CREATE TABLE transcript_segments (
+    content_id UUID NOT NULL REFERENCES contents(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    start_seconds DOUBLE PRECISION NOT NULL,
+    end_seconds DOUBLE PRECISION NOT NULL,
+    text TEXT NOT NULL,
+    PRIMARY KEY (content_id, position)
+);
@@ -0,0 +1,31 @@
+CREATE TABLE feeds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    url TEXT NOT NULL UNIQUE,
+    title TEXT,
+    link TEXT,
+    etag TEXT,
+    last_polled_at TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE feed_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    guid TEXT NOT NULL,
+    title TEXT,
+    link TEXT,
+    enclosure_url TEXT NOT NULL,
+    published_at TIMESTAMP,
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    content_id UUID REFERENCES contents(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (feed_id, guid)
+);
+
+CREATE INDEX idx_feed_items_feed_id
+ON feed_items (feed_id, published_at DESC);
+
+CREATE INDEX idx_feed_items_status
+ON feed_items (status);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS channel_watch_videos;
+DROP TABLE IF EXISTS channel_watches;
@@ -0,0 +1,28 @@
+CREATE TABLE channel_watches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    channel_key TEXT NOT NULL UNIQUE,
+    url TEXT NOT NULL,
+    title TEXT,
+    check_interval_seconds INTEGER NOT NULL,
+    last_checked_at TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE channel_watch_videos (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    watch_id UUID NOT NULL REFERENCES channel_watches(id) ON DELETE CASCADE,
+    video_id VARCHAR(20) NOT NULL,
+    title TEXT,
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    content_id UUID REFERENCES contents(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (watch_id, video_id)
+);
+
+CREATE INDEX idx_channel_watch_videos_watch_id
+ON channel_watch_videos (watch_id, created_at DESC);
+
+CREATE INDEX idx_channel_watch_videos_status
+ON channel_watch_videos (status);
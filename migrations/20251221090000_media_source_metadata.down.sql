@@ -0,0 +1,2 @@
+ALTER TABLE media_sources
+DROP COLUMN IF EXISTS metadata;
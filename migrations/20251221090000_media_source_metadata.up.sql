@@ -0,0 +1,2 @@
+ALTER TABLE media_sources
+ADD COLUMN metadata JSONB;
@@ -0,0 +1,11 @@
+CREATE TABLE keyframes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    content_id UUID NOT NULL REFERENCES contents(id) ON DELETE CASCADE,
+    time_seconds DOUBLE PRECISION NOT NULL,
+    object_key TEXT NOT NULL,
+    caption TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_keyframes_content_id
+ON keyframes (content_id, time_seconds);
@@ -0,0 +1,3 @@
+ALTER TABLE contents
+    DROP COLUMN IF EXISTS loudness,
+    DROP COLUMN IF EXISTS preprocess;
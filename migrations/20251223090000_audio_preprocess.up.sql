@@ -0,0 +1,3 @@
+ALTER TABLE contents
+    ADD COLUMN preprocess TEXT NOT NULL DEFAULT '',
+    ADD COLUMN loudness JSONB;
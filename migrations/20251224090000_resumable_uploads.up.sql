@@ -0,0 +1,17 @@
+CREATE TABLE resumable_uploads (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    upload_length BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    object_key TEXT NOT NULL,
+    multipart_id TEXT NOT NULL,
+    part_etags TEXT[] NOT NULL DEFAULT '{}',
+    checksum TEXT,
+    metadata JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'uploading',
+    job_id TEXT,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+ALTER TABLE contents
+    DROP COLUMN IF EXISTS transcriber;
@@ -0,0 +1,10 @@
+ALTER TABLE contents
+    ADD COLUMN transcriber TEXT NOT NULL DEFAULT '';
+
+-- summaries from before the column existed are taken to be whisper's, the
+-- default; media summaries without segments came from captions
+UPDATE contents SET transcriber = 'whisper'
+WHERE id IN (SELECT content_id FROM transcript_segments);
+
+UPDATE contents SET transcriber = 'captions'
+WHERE source_id IS NOT NULL AND parent_id IS NULL AND collection_id IS NULL AND transcriber = '';
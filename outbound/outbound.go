@@ -0,0 +1,222 @@
+// Package outbound is the only way the service should reach URLs that came
+// from users. Every connection is checked after DNS resolution, so a public
+// hostname that resolves to an internal address is refused as well.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrSchemeNotAllowed = errors.New("url scheme is not allowed")
+	ErrHostNotAllowed   = errors.New("host is not allowed")
+	ErrAddressBlocked   = errors.New("address is not publicly routable")
+)
+
+// Policy decides which URLs may be fetched. Host lists match the host itself
+// and any subdomain of it; an empty AllowHosts allows every host that is not
+// denied.
+type Policy struct {
+	Schemes    []string
+	AllowHosts []string
+	DenyHosts  []string
+}
+
+// Default is the policy used by Client and Validate. main fills the host
+// lists in from the environment before anything is fetched.
+var Default = &Policy{Schemes: []string{"http", "https"}}
+
+// MaxRedirects caps redirect chains; every hop is validated on its own.
+const MaxRedirects = 5
+
+// blockedPrefixes are ranges that net/netip has no predicate for.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 can reach embedded IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+func (p *Policy) CheckURL(u *url.URL) error {
+	scheme := strings.ToLower(u.Scheme)
+	ok := false
+	for _, s := range p.Schemes {
+		if s == scheme {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrSchemeNotAllowed, u.Scheme)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: urls with credentials are not fetched", ErrHostNotAllowed)
+	}
+	return p.CheckHost(u.Hostname())
+}
+
+func (p *Policy) CheckHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return fmt.Errorf("%w: empty host", ErrHostNotAllowed)
+	}
+	if matchHost(host, p.DenyHosts) {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+	if len(p.AllowHosts) > 0 && !matchHost(host, p.AllowHosts) {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrAddressBlocked, host)
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return CheckIP(ip)
+	}
+	return nil
+}
+
+func matchHost(host string, list []string) bool {
+	for _, h := range list {
+		h = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(h)), ".")
+		if h != "" && (host == h || strings.HasSuffix(host, "."+h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIP rejects loopback, private, link-local (including cloud metadata at
+// 169.254.169.254), multicast and other non-public addresses.
+func CheckIP(ip netip.Addr) error {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", ErrAddressBlocked, ip)
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return fmt.Errorf("%w: %s", ErrAddressBlocked, ip)
+		}
+	}
+	return nil
+}
+
+// Validate checks a URL against the policy and resolves its host, failing if
+// any address it resolves to is blocked. It gives tools that run behind a
+// Proxy a clear error up front; the HTTP client below re-checks at dial time
+// instead.
+func (p *Policy) Validate(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if err := p.CheckURL(u); err != nil {
+		return err
+	}
+
+	host := u.Hostname()
+	if _, err := netip.ParseAddr(host); err == nil {
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, a := range addrs {
+		if err := CheckIP(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Validate(ctx context.Context, rawURL string) error {
+	return Default.Validate(ctx, rawURL)
+}
+
+// newDialer returns a dialer that refuses every connection to an address
+// CheckIP rejects, whatever the host name resolved to.
+func newDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrAddressBlocked, address)
+			}
+			return CheckIP(ap.Addr())
+		},
+	}
+}
+
+// newTransport ignores proxies from the environment, since they would hide
+// the real target.
+func newTransport(dialer *net.Dialer) *http.Transport {
+	return &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	}
+}
+
+// NewClient returns an HTTP client that enforces p on the first request, on
+// every redirect and on the resolved address of every connection.
+func NewClient(p *Policy) *http.Client {
+	transport := newTransport(newDialer())
+
+	return &http.Client{
+		Transport: &guardTransport{policy: p, base: transport},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= MaxRedirects {
+				return errors.New("too many redirects")
+			}
+			return p.CheckURL(req.URL)
+		},
+	}
+}
+
+// guardTransport validates the URL of every request that reaches the
+// network, so callers cannot skip the check on the first hop.
+type guardTransport struct {
+	policy *Policy
+	base   http.RoundTripper
+}
+
+func (t *guardTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.policy.CheckURL(req.URL); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+var (
+	clientOnce sync.Once
+	client     *http.Client
+)
+
+// Client is the shared client for the Default policy.
+func Client() *http.Client {
+	clientOnce.Do(func() {
+		client = NewClient(Default)
+	})
+	return client
+}
@@ -0,0 +1,156 @@
+package outbound
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Proxy is a forward proxy on the loopback interface for tools such as
+// yt-dlp that open their own connections. Plain requests go through the
+// policy's transport and CONNECT tunnels are dialed with the same checked
+// dialer, so redirects the tool follows and the addresses it resolves are
+// held to the policy like those of Client.
+type Proxy struct {
+	policy    *Policy
+	dialer    *net.Dialer
+	transport http.RoundTripper
+	ln        net.Listener
+	srv       *http.Server
+	wg        sync.WaitGroup
+}
+
+// hopHeaders are not forwarded to the target.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// StartProxy listens on a random loopback port until Close is called.
+func (p *Policy) StartProxy() (*Proxy, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	dialer := newDialer()
+	pr := &Proxy{
+		policy:    p,
+		dialer:    dialer,
+		transport: &guardTransport{policy: p, base: newTransport(dialer)},
+		ln:        ln,
+	}
+	pr.srv = &http.Server{Handler: pr, ReadHeaderTimeout: 10 * time.Second}
+	pr.wg.Add(1)
+	go func() {
+		defer pr.wg.Done()
+		pr.srv.Serve(ln)
+	}()
+	return pr, nil
+}
+
+// URL is the value to pass as the tool's proxy setting.
+func (pr *Proxy) URL() string {
+	return "http://" + pr.ln.Addr().String()
+}
+
+// Close stops the proxy. Tunnels still open end once the tool closes its side.
+func (pr *Proxy) Close() error {
+	err := pr.srv.Close()
+	pr.wg.Wait()
+	return err
+}
+
+func (pr *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		pr.tunnel(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "proxy requests need an absolute url", http.StatusBadRequest)
+		return
+	}
+
+	req := r.Clone(r.Context())
+	req.RequestURI = ""
+	for _, h := range hopHeaders {
+		req.Header.Del(h)
+	}
+	resp, err := pr.transport.RoundTrip(req)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// tunnel serves CONNECT, which is how https goes through the proxy. The host
+// is checked against the policy and the dialer checks the address it
+// resolves to.
+func (pr *Proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		http.Error(w, "invalid CONNECT target", http.StatusBadRequest)
+		return
+	}
+	if err := pr.policy.CheckHost(host); err != nil {
+		proxyError(w, err)
+		return
+	}
+	target, err := pr.dialer.DialContext(r.Context(), "tcp", r.Host)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		target.Close()
+		http.Error(w, "tunneling is not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		target.Close()
+		return
+	}
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		conn.Close()
+		target.Close()
+		return
+	}
+
+	go func() {
+		// rw holds whatever the client sent after the CONNECT request
+		io.Copy(target, rw)
+		target.Close()
+	}()
+	io.Copy(conn, target)
+	conn.Close()
+}
+
+func proxyError(w http.ResponseWriter, err error) {
+	code := http.StatusBadGateway
+	if errors.Is(err, ErrSchemeNotAllowed) || errors.Is(err, ErrHostNotAllowed) || errors.Is(err, ErrAddressBlocked) {
+		code = http.StatusForbidden
+	}
+	http.Error(w, err.Error(), code)
+}
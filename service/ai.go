@@ -9,7 +9,21 @@ import (
 	"google.golang.org/genai"
 )
 
-func (s *Service) AiGenResponse(ctx context.Context, text, source string) (string, error) {
+// summaryTasks are the numbered rules every summary follows; extra
+// instructions continue the numbering at 9.
+const summaryTasks = `1. Read the content carefully and extract the most important points.
+2. Summarize the content in **clear, concise, and coherent paragraphs**.
+3. Focus on the **core ideas, key facts, and main messages** only.
+4. Ignore filler words, background noise, timestamps, speaker labels, and metadata.
+5. If the content is conversational (like a video or audio), summarize the key points as if explaining to someone who hasn’t seen it.
+6. If the content contains multiple topics, organize them logically in the summary.
+7. Lines starting with "#" are section headings and lines starting with "-" are list items from the original document; use them to follow its structure.
+8. Your response should be **plain text only**, no markdown, no lists, no headings.`
+
+// AiGenResponse summarizes text. Any extra instructions are appended to the
+// numbered task list, for sources that need more than the generic prompt
+// (e.g. citing slide or page numbers).
+func (s *Service) AiGenResponse(ctx context.Context, text, source string, extra ...string) (string, error) {
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
 		APIKey: os.Getenv("GEMINI_API_KEY"),
 	})
@@ -23,19 +37,13 @@ You are a professional content summarization AI.
 The content below was extracted from: %s
 
 Your tasks:
-1. Read the content carefully and extract the most important points.
-2. Summarize the content in **clear, concise, and coherent paragraphs**.
-3. Focus on the **core ideas, key facts, and main messages** only.
-4. Ignore filler words, background noise, timestamps, speaker labels, and metadata.
-5. If the content is conversational (like a video or audio), summarize the key points as if explaining to someone who hasn’t seen it.
-6. If the content contains multiple topics, organize them logically in the summary.
-7. Your response should be **plain text only**, no markdown, no lists, no headings.
-
+%s
+%s
 Content:
 %s
 
 Return ONLY the summary.
-`, source, text)
+`, source, summaryTasks, extraTasks(9, extra), text)
 
 	resp, err := client.Models.GenerateContent(ctx, "gemini-2.5-flash", genai.Text(prompt), nil)
 	if err != nil {
@@ -51,3 +59,42 @@ Return ONLY the summary.
 
 	return summary.String(), nil
 }
+
+func extraTasks(start int, extra []string) string {
+	var sb strings.Builder
+	for i, e := range extra {
+		fmt.Fprintf(&sb, "%d. %s\n", start+i, e)
+	}
+	return sb.String()
+}
+
+// AiDescribeFrame captions a still taken from a video. narration is what was
+// being said around it, so the caption can name what the speaker refers to.
+func (s *Service) AiDescribeFrame(ctx context.Context, image []byte, narration string) (string, error) {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey: os.Getenv("GEMINI_API_KEY"),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	prompt := fmt.Sprintf(`
+This image is a frame from a video. Describe in one or two sentences what is on screen, such as the slide, code, diagram or scene shown, and transcribe any headline text.
+
+What was being said around this frame, for context:
+%s
+
+Return ONLY the description, as plain text.
+`, narration)
+
+	resp, err := client.Models.GenerateContent(ctx, "gemini-2.5-flash", []*genai.Content{
+		genai.NewContentFromParts([]*genai.Part{
+			genai.NewPartFromBytes(image, "image/jpeg"),
+			genai.NewPartFromText(prompt),
+		}, genai.RoleUser),
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Text()), nil
+}
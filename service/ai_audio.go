@@ -0,0 +1,243 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lupppig/briefly/db/mini"
+	db "github.com/lupppig/briefly/db/postgres"
+	"google.golang.org/genai"
+)
+
+// Transcriber picks how a recording is turned into a transcript.
+type Transcriber string
+
+const (
+	// TranscriberWhisper runs whisper.cpp locally and summarizes the
+	// transcript in a second call.
+	TranscriberWhisper Transcriber = "whisper"
+	// TranscriberGemini sends the audio to Gemini, which returns the
+	// transcript and the summary in one call. Whisper is used whenever it
+	// fails.
+	TranscriberGemini Transcriber = "gemini"
+)
+
+// TranscriberCaptions marks transcripts taken from the platform's captions
+// rather than transcribed; see PreferCaptions.
+const TranscriberCaptions Transcriber = "captions"
+
+var ErrUnknownTranscriber = errors.New(`transcriber must be "whisper" or "gemini"`)
+
+// ParseTranscriber reads the transcriber of a request; empty means whisper.
+func ParseTranscriber(s string) (Transcriber, error) {
+	switch Transcriber(strings.ToLower(strings.TrimSpace(s))) {
+	case "", TranscriberWhisper:
+		return TranscriberWhisper, nil
+	case TranscriberGemini:
+		return TranscriberGemini, nil
+	}
+	return "", ErrUnknownTranscriber
+}
+
+var (
+	// MaxInlineAudioBytes is the largest WAV sent inline with the request;
+	// larger ones go through the Gemini Files API. Inline data is base64
+	// encoded into a request capped at 20 MB.
+	MaxInlineAudioBytes = 14 << 20
+	// MaxGeminiAudio is the longest recording sent to Gemini. The transcript
+	// has to fit in one response, so longer ones go to whisper.
+	MaxGeminiAudio = time.Hour
+	// GeminiFileTimeout bounds how long an uploaded file may stay processing.
+	GeminiFileTimeout = 2 * time.Minute
+)
+
+// wavBytesPerSecond is the rate of the 16 kHz mono 16-bit WAVs stored for
+// transcription.
+const wavBytesPerSecond = 16000 * 2
+
+// AudioResponse is the transcript and summary Gemini returns for a recording.
+type AudioResponse struct {
+	Segments []db.TranscriptSegment
+	Summary  string
+}
+
+// AiAudioResponse transcribes and summarizes a WAV from MinIO in one Gemini
+// call. offset is added to every timestamp, like TranscribeSegments does.
+// notes are the creator's description and chapters, if any.
+func (s *Service) AiAudioResponse(ctx context.Context, audioKey string, offset time.Duration, source, notes string, extra ...string) (*AudioResponse, error) {
+	buf, err := s.Mc.GetObjectBuffer(mini.DocumentBucket, audioKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio from MinIO: %w", err)
+	}
+	if time.Duration(buf.Len()/wavBytesPerSecond)*time.Second > MaxGeminiAudio {
+		return nil, fmt.Errorf("recording is longer than %s", MaxGeminiAudio)
+	}
+
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey: os.Getenv("GEMINI_API_KEY"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var audio *genai.Part
+	if buf.Len() <= MaxInlineAudioBytes {
+		audio = genai.NewPartFromBytes(buf.Bytes(), "audio/wav")
+	} else {
+		file, err := uploadGeminiFile(ctx, client, buf.Bytes(), "audio/wav")
+		if err != nil {
+			return nil, err
+		}
+		defer client.Files.Delete(context.Background(), file.Name, nil)
+		audio = genai.NewPartFromURI(file.URI, file.MIMEType)
+	}
+
+	if notes != "" {
+		notes = "Notes from the creator, to use for names, context and structure only:\n" + notes
+	}
+	prompt := fmt.Sprintf(`
+You are a professional transcription and content summarization AI.
+
+The attached audio is from: %s
+%s
+First transcribe everything that is said, word for word, in segments of one or two sentences. Give each segment its start and end time in seconds from the beginning of the audio.
+
+Then summarize what is said. Your tasks:
+%s
+%s
+Return the transcript segments and the summary in the requested JSON format.
+`, source, notes, summaryTasks, extraTasks(9, extra))
+
+	resp, err := client.Models.GenerateContent(ctx, "gemini-2.5-flash", []*genai.Content{
+		genai.NewContentFromParts([]*genai.Part{audio, genai.NewPartFromText(prompt)}, genai.RoleUser),
+	}, &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema:   audioResponseSchema,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAudioResponse(resp.Text(), offset)
+}
+
+var audioResponseSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"segments": {
+			Type: genai.TypeArray,
+			Items: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"start": {Type: genai.TypeNumber},
+					"end":   {Type: genai.TypeNumber},
+					"text":  {Type: genai.TypeString},
+				},
+				Required: []string{"start", "end", "text"},
+			},
+		},
+		"summary": {Type: genai.TypeString},
+	},
+	Required: []string{"segments", "summary"},
+}
+
+// parseAudioResponse checks the model's JSON and shifts its times by offset.
+// Segments come back roughly ordered at best, so they are sorted and
+// overlapping ends are clamped.
+func parseAudioResponse(text string, offset time.Duration) (*AudioResponse, error) {
+	var out struct {
+		Segments []db.TranscriptSegment `json:"segments"`
+		Summary  string                 `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse gemini transcript: %w", err)
+	}
+
+	res := &AudioResponse{Summary: strings.TrimSpace(out.Summary)}
+	for _, seg := range out.Segments {
+		seg.Text = strings.TrimSpace(seg.Text)
+		if seg.Text == "" || seg.Start < 0 {
+			continue
+		}
+		if seg.End < seg.Start {
+			seg.End = seg.Start
+		}
+		seg.Start += offset.Seconds()
+		seg.End += offset.Seconds()
+		res.Segments = append(res.Segments, seg)
+	}
+	if res.Summary == "" || len(res.Segments) == 0 {
+		return nil, errors.New("gemini returned an empty transcript or summary")
+	}
+
+	sort.SliceStable(res.Segments, func(i, j int) bool { return res.Segments[i].Start < res.Segments[j].Start })
+	for i := 0; i+1 < len(res.Segments); i++ {
+		if next := res.Segments[i+1].Start; res.Segments[i].End > next {
+			res.Segments[i].End = next
+		}
+	}
+	return res, nil
+}
+
+// uploadGeminiFile uploads data through the Files API and waits until it can
+// be referenced in a request.
+func uploadGeminiFile(ctx context.Context, client *genai.Client, data []byte, mimeType string) (*genai.File, error) {
+	file, err := client.Files.Upload(ctx, bytes.NewReader(data), &genai.UploadFileConfig{MIMEType: mimeType})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload audio to gemini: %w", err)
+	}
+
+	deadline := time.Now().Add(GeminiFileTimeout)
+	for file.State == genai.FileStateProcessing {
+		if time.Now().After(deadline) {
+			client.Files.Delete(context.Background(), file.Name, nil)
+			return nil, errors.New("gemini took too long to process the audio")
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+		if file, err = client.Files.Get(ctx, file.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+	if file.State == genai.FileStateFailed {
+		return nil, errors.New("gemini failed to process the audio")
+	}
+	return file, nil
+}
+
+// transcribe turns a stored WAV into segments with the chosen transcriber
+// and reports the one that was used. summary is only set when the
+// transcriber summarized as well; Gemini failures are logged and fall back to
+// whisper.
+func (s *Service) transcribe(ctx context.Context, t Transcriber, audioKey string, offset time.Duration, source, notes string, extra ...string) (segments []db.TranscriptSegment, summary string, used Transcriber, err error) {
+	if t == TranscriberGemini {
+		res, err := s.AiAudioResponse(ctx, audioKey, offset, source, notes, extra...)
+		if err == nil {
+			return res.Segments, res.Summary, TranscriberGemini, nil
+		}
+		log.Printf("gemini transcription of %s failed, falling back to whisper: %v", audioKey, err)
+	}
+
+	segments, err = s.TranscribeSegments(audioKey, offset)
+	return segments, "", TranscriberWhisper, err
+}
+
+// cacheKey is how t is stored with summaries; options built in code leave it
+// empty, which means whisper.
+func (t Transcriber) cacheKey() string {
+	if t == "" {
+		return string(TranscriberWhisper)
+	}
+	return string(t)
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lupppig/briefly/media"
+	"github.com/lupppig/briefly/utils"
+)
+
+var (
+	// MaxArchiveUploadBytes caps the size of an uploaded zip file.
+	MaxArchiveUploadBytes int64 = 512 << 20
+	// MaxArchiveEntries caps how many files an archive may hold, including
+	// the ones that are skipped.
+	MaxArchiveEntries = 100
+	// MaxArchiveUncompressed caps what all entries of an archive add up to
+	// once inflated.
+	MaxArchiveUncompressed int64 = 1 << 30
+	// MaxArchiveDocument caps a single document or image entry, the same as
+	// a document uploaded on its own. Audio and video are only held to
+	// MaxArchiveUncompressed.
+	MaxArchiveDocument int64 = 20 << 20
+)
+
+var (
+	ErrNotArchive        = errors.New("file is not a zip archive")
+	ErrArchiveUnsafePath = errors.New("archive contains a path that points outside of it")
+	ErrArchiveEntries    = fmt.Errorf("archive holds more than %d files", MaxArchiveEntries)
+	ErrArchiveTooLarge   = fmt.Errorf("archive expands to more than %d MB", MaxArchiveUncompressed>>20)
+	ErrArchiveEmpty      = errors.New("archive holds no supported files")
+
+	errArchiveEntryTooLarge = errors.New("archive entry is too large")
+)
+
+// Archive is a zip upload expanded into a temporary directory, which is
+// removed by ProcessArchiveJob once every entry has run.
+type Archive struct {
+	Name    string
+	Dir     string
+	Entries []ArchiveEntry
+	// Skipped lists the entries that are not a supported file, as well as
+	// hidden files, symlinks and documents over MaxArchiveDocument.
+	Skipped []string
+}
+
+type ArchiveEntry struct {
+	// Name is the path of the entry inside the archive. It is only shown,
+	// never used on disk.
+	Name string
+	Path string
+	Size int64
+}
+
+// ExpandArchive checks a zip upload against the archive limits and writes
+// its supported entries to a temporary directory. Entries are written under
+// generated names, and archives with absolute paths or ".." in any entry are
+// rejected outright.
+func ExpandArchive(r io.ReaderAt, size int64, name string) (*Archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if errors.Is(err, zip.ErrInsecurePath) {
+		return nil, ErrArchiveUnsafePath
+	}
+	if err != nil {
+		return nil, ErrNotArchive
+	}
+
+	var (
+		files    []*zip.File
+		declared uint64
+	)
+	for _, f := range zr.File {
+		if !localArchivePath(f.Name) {
+			return nil, ErrArchiveUnsafePath
+		}
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		files = append(files, f)
+		declared += f.UncompressedSize64
+	}
+	if len(files) > MaxArchiveEntries {
+		return nil, ErrArchiveEntries
+	}
+	// the declared sizes are only a cheap first check; they are not trusted
+	// while inflating
+	if declared > uint64(MaxArchiveUncompressed) {
+		return nil, ErrArchiveTooLarge
+	}
+
+	dir, err := os.MkdirTemp("", "archive-*")
+	if err != nil {
+		return nil, err
+	}
+	a := &Archive{Name: name, Dir: dir}
+	budget := MaxArchiveUncompressed
+	for i, f := range files {
+		if skipArchiveEntry(f) {
+			a.Skipped = append(a.Skipped, f.Name)
+			continue
+		}
+		limit := budget
+		if !isMediaType(utils.ContentTypeByExtension(f.Name)) {
+			if f.UncompressedSize64 > uint64(MaxArchiveDocument) {
+				a.Skipped = append(a.Skipped, f.Name)
+				continue
+			}
+			limit = min(limit, MaxArchiveDocument)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%03d%s", i, strings.ToLower(filepath.Ext(f.Name))))
+		n, err := writeArchiveEntry(f, path, limit)
+		if errors.Is(err, errArchiveEntryTooLarge) && limit < budget {
+			// the entry claimed to be smaller than it inflates to
+			os.Remove(path)
+			a.Skipped = append(a.Skipped, f.Name)
+			continue
+		}
+		if errors.Is(err, errArchiveEntryTooLarge) {
+			err = ErrArchiveTooLarge
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+		budget -= n
+		a.Entries = append(a.Entries, ArchiveEntry{Name: f.Name, Path: path, Size: n})
+	}
+	if len(a.Entries) == 0 {
+		os.RemoveAll(dir)
+		return nil, ErrArchiveEmpty
+	}
+	return a, nil
+}
+
+// localArchivePath reports whether an entry name stays inside the archive
+// root. Backslashes count as separators, as Windows tools write them.
+func localArchivePath(name string) bool {
+	name = strings.ReplaceAll(name, `\`, "/")
+	return name != "" && filepath.IsLocal(filepath.FromSlash(name))
+}
+
+func skipArchiveEntry(f *zip.File) bool {
+	// symlinks and devices
+	if !f.Mode().IsRegular() {
+		return true
+	}
+	for _, part := range strings.Split(strings.ReplaceAll(f.Name, `\`, "/"), "/") {
+		// .DS_Store, ._ resource forks and the __MACOSX folder
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return utils.ContentTypeByExtension(f.Name) == ""
+}
+
+// writeArchiveEntry inflates f into path, failing once more than limit
+// bytes come out.
+func writeArchiveEntry(f *zip.File, path string, limit int64) (int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	n, err := io.Copy(out, io.LimitReader(rc, limit+1))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	if n > limit {
+		return 0, errArchiveEntryTooLarge
+	}
+	return n, nil
+}
+
+// ProcessArchiveJob runs every entry of an expanded archive through the
+// upload pipeline as a child job and groups the results under a collection,
+// the same way ProcessBatchJob does for links.
+func (s *Service) ProcessArchiveJob(jobID string, a *Archive, opts BatchOptions) {
+	defer os.RemoveAll(a.Dir)
+
+	update := func(status string, summary interface{}, errMsg string) {
+		s.JobManager.UpdateJob(jobID, status, summary, errMsg)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			update("error", "", fmt.Sprintf("panic: %v", r))
+		}
+	}()
+
+	uploadOpts := UploadOptions{Transcriber: opts.Transcriber, Preprocess: opts.Preprocess}
+	var tasks []childTask
+	for _, e := range a.Entries {
+		tasks = append(tasks, childTask{
+			Title: e.Name,
+			run:   func(id string) { s.processArchiveEntry(id, e, uploadOpts) },
+		})
+	}
+
+	ctx := context.Background()
+	title := opts.Title
+	if title == "" {
+		title = strings.TrimSuffix(a.Name, filepath.Ext(a.Name))
+	}
+	coll, err := s.Db.CreateCollection(ctx, "archive", a.Name, title)
+	if err != nil {
+		update("error", "", "failed db fetch")
+		return
+	}
+	result := &CollectionResult{Collection: coll, Skipped: a.Skipped}
+
+	update("processing_files", "", "")
+	result.Items = s.runChildJobs(jobID, tasks)
+
+	if opts.Combine {
+		update("summarizing", "", "")
+	}
+	source := fmt.Sprintf("the summaries of the files in the archive %q", title)
+	if err := s.groupCollection(ctx, result, opts.Combine, source); err != nil {
+		update("error", result, err.Error())
+		return
+	}
+
+	update("done", result, "")
+}
+
+// processArchiveEntry hands one expanded entry to AudioDocService as if it
+// had been uploaded on its own. Preprocessing is dropped for documents.
+func (s *Service) processArchiveEntry(jobID string, e ArchiveEntry, opts UploadOptions) {
+	update := func(status string, summary interface{}, errMsg string) {
+		s.JobManager.UpdateJob(jobID, status, summary, errMsg)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			update("error", "", fmt.Sprintf("panic: %v", r))
+		}
+	}()
+
+	f, err := os.Open(e.Path)
+	if err != nil {
+		update("error", "", err.Error())
+		return
+	}
+	defer f.Close()
+
+	fh := &multipart.FileHeader{
+		Filename: filepath.Base(filepath.FromSlash(strings.ReplaceAll(e.Name, `\`, "/"))),
+		Size:     e.Size,
+		Header:   textproto.MIMEHeader{"Content-Type": {utils.ContentTypeByExtension(e.Name)}},
+	}
+	if utils.IsDoc(f, fh) {
+		opts.Preprocess = media.Preprocess{}
+	}
+
+	update("processing", "", "")
+	summ, err := s.AudioDocService(f, fh, opts)
+	if err != nil {
+		update("error", "", err.Error())
+		return
+	}
+	update("done", summ, "")
+}
@@ -1,21 +1,20 @@
 package service
 
 import (
-	"bufio"
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
-	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
 	"github.com/lupppig/briefly/db/mini"
 	db "github.com/lupppig/briefly/db/postgres"
+	"github.com/lupppig/briefly/extract"
+	"github.com/lupppig/briefly/media"
 	"github.com/lupppig/briefly/utils"
 )
 
@@ -28,6 +27,14 @@ type Service struct {
 
 var modelsPath string = "models/ggml-base.en.bin"
 
+var (
+	ErrNoText           = errors.New("no text could be extracted from the file")
+	ErrRangeNotMedia    = errors.New("time ranges only apply to audio and video")
+	ErrPreprocessDoc    = errors.New("audio preprocessing only applies to audio and video")
+	ErrRangeOutOfBounds = errors.New("time range starts after the end of the recording")
+	ErrFileNotFound     = errors.New("file not found")
+)
+
 func NewService(db *db.PostgresDB, m *mini.MinioClient) (*Service, error) {
 	model, err := whisper.New(modelsPath)
 	if err != nil {
@@ -36,30 +43,61 @@ func NewService(db *db.PostgresDB, m *mini.MinioClient) (*Service, error) {
 	return &Service{Db: db, Mc: m, WhisperModel: model, JobManager: NewJobManager()}, nil
 }
 
-func (s *Service) AudioDocService(fi multipart.File, fh *multipart.FileHeader) (*db.SummaryContent, error) {
+// UploadOptions carries per-request settings that are not part of the file
+// itself. None of them are persisted.
+type UploadOptions struct {
+	// PDFPassword decrypts password-protected PDFs.
+	PDFPassword string
+	// Range limits audio and video uploads to part of the recording.
+	Range media.TimeRange
+	// Transcriber picks whisper (the default) or Gemini for audio and video.
+	Transcriber Transcriber
+	// Preprocess filters audio and video before transcription.
+	Preprocess media.Preprocess
+}
+
+func (s *Service) AudioDocService(fi multipart.File, fh *multipart.FileHeader, opts UploadOptions) (*db.SummaryContent, error) {
+	respDoc, audioKey, err := s.StoreUpload(fi, fh, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.summarizeFile(respDoc, audioKey, opts)
+}
+
+// StoreUpload validates an upload, stores it and records it as a document
+// without summarizing it. The returned key is the derived WAV of audio and
+// video files.
+func (s *Service) StoreUpload(fi multipart.File, fh *multipart.FileHeader, opts UploadOptions) (*db.DocumentAudio, string, error) {
 	var objKey string
 
 	if err := utils.ValidateUploadedFile(fh); err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	if err := opts.Range.Validate(); err != nil {
+		return nil, "", err
 	}
 
 	hashedFile, err := utils.HashFile(fi)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	fi.Seek(0, io.SeekStart)
-
-	if utils.IsDoc(fi, fh.Filename) {
-		objKey = filepath.Join("uploads", "doc", hashedFile)
-	} else {
-		objKey = filepath.Join("uploads", "audio", hashedFile)
+	mimeType := extract.DetectMIME(fi, fh.Size, fh.Filename)
+	isDoc := extract.Supported(mimeType)
+	isVideo := !isDoc && utils.IsVideo(fh.Filename)
+	if isDoc && !opts.Range.IsZero() {
+		return nil, "", ErrRangeNotMedia
 	}
+	if isDoc && !opts.Preprocess.IsZero() {
+		return nil, "", ErrPreprocessDoc
+	}
+
+	objKey = uploadKey(hashedFile, isDoc, isVideo)
 
 	objExist, err := s.Mc.ObjectExists(mini.DocumentBucket, objKey)
 	if err != nil {
 		log.Printf("failed to check object in MinIO: %v", err)
-		return nil, err
+		return nil, "", err
 	}
 
 	if !objExist {
@@ -67,132 +105,355 @@ func (s *Service) AudioDocService(fi multipart.File, fh *multipart.FileHeader) (
 		_, err := s.Mc.AddToBucket(context.Background(), fi, fh, mini.DocumentBucket, objKey)
 		if err != nil {
 			log.Printf("failed to upload object to MinIO: %v", err)
-			return nil, err
+			return nil, "", err
 		}
 	}
 
 	var durationInSec *float64
 	var pageCount *int
+	var audioKey string
+	var probe *media.Info
+	fileType := "audio"
 	fi.Seek(0, io.SeekStart)
-	if utils.IsDoc(fi, fh.Filename) {
-		count, err := utils.GetPDFPageCount(fi)
-		if err != nil {
-			log.Printf("could not get PDF page count: %v", err)
-			return nil, err
+	switch {
+	case isDoc:
+		fileType = "document"
+		if strings.HasPrefix(mimeType, "image/") {
+			fileType = "image"
 		}
-		pageCount = &count
-	} else {
-		dur, err := utils.GettMP3Duration(fi)
+		switch mimeType {
+		case extract.MimePDF:
+			count, err := utils.GetPDFPageCount(fi, opts.PDFPassword)
+			if err != nil {
+				log.Printf("could not get PDF page count: %v", err)
+				return nil, "", err
+			}
+			pageCount = &count
+		case extract.MimePPTX, extract.MimeODP:
+			count, err := utils.GetSlideCount(fi, mimeType)
+			if err != nil {
+				log.Printf("could not get slide count: %v", err)
+				return nil, "", err
+			}
+			pageCount = &count
+		}
+	default:
+		if isVideo {
+			fileType = "video"
+		}
+		mimeType = fh.Header.Get("Content-Type")
+		key, info, err := s.DeriveUploadAudio(context.Background(), fi, hashedFile, filepath.Ext(fh.Filename))
 		if err != nil {
-			log.Printf("could not get audio duration: %v", err)
-			return nil, err
+			log.Printf("could not extract audio: %v", err)
+			return nil, "", err
+		}
+		audioKey = key
+		probe = info
+		durationInSec = &info.Duration
+		if info.Duration > 0 && opts.Range.Start.Seconds() >= info.Duration {
+			return nil, "", ErrRangeOutOfBounds
 		}
-		durationInSec = &dur
 	}
 
 	doc := db.DocumentAudio{
 		Name:            fh.Filename,
-		FileType:        fh.Header.Get("Content-Type"),
+		FileType:        fileType,
+		MimeType:        mimeType,
 		StoragePath:     objKey,
 		Size:            fh.Size,
 		FileHash:        hashedFile,
 		DurationSeconds: durationInSec,
 		PageCount:       pageCount,
 	}
+	if probe != nil {
+		doc.SampleRate = &probe.SampleRate
+		doc.Channels = &probe.Channels
+		doc.Codec = &probe.Codec
+		doc.Bitrate = &probe.Bitrate
+	}
 
 	respDoc, err := s.Db.GetOrCreateDocument(context.Background(), doc)
 	if err != nil {
 		log.Printf("failed to get or create document in DB: %v", err)
+		return nil, "", err
+	}
+
+	return respDoc, audioKey, nil
+}
+
+// SummarizeStoredFile summarizes a file uploaded earlier, by its ID, the same
+// way AudioDocService does for a new upload.
+func (s *Service) SummarizeStoredFile(fileID string, opts UploadOptions) (*db.SummaryContent, error) {
+	if err := opts.Range.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	doc, err := s.Db.GetDocument(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, ErrFileNotFound
+	}
+	if doc.FileType == "document" || doc.FileType == "image" {
+		if !opts.Range.IsZero() {
+			return nil, ErrRangeNotMedia
+		}
+		if !opts.Preprocess.IsZero() {
+			return nil, ErrPreprocessDoc
+		}
+		return s.summarizeFile(doc, "", opts)
+	}
+
+	if doc.DurationSeconds != nil && *doc.DurationSeconds > 0 && opts.Range.Start.Seconds() >= *doc.DurationSeconds {
+		return nil, ErrRangeOutOfBounds
+	}
+
+	// the derived WAV is kept from the upload; it is only rebuilt from the
+	// stored original if it has gone missing
+	audioKey := filepath.Join("uploads", "audio", doc.FileHash+".wav")
+	exists, err := s.Mc.ObjectExists(mini.DocumentBucket, audioKey)
+	if err != nil {
 		return nil, err
 	}
+	if !exists {
+		buf, err := s.Mc.GetObjectBuffer(mini.DocumentBucket, doc.StoragePath)
+		if err != nil {
+			return nil, err
+		}
+		if audioKey, _, err = s.DeriveUploadAudio(ctx, buf, doc.FileHash, filepath.Ext(doc.Name)); err != nil {
+			return nil, fmt.Errorf("could not extract audio: %w", err)
+		}
+	}
+	return s.summarizeFile(doc, audioKey, opts)
+}
+
+// SummarizeUpload summarizes a file StoreUpload has stored.
+func (s *Service) SummarizeUpload(doc *db.DocumentAudio, audioKey string, opts UploadOptions) (*db.SummaryContent, error) {
+	return s.summarizeFile(doc, audioKey, opts)
+}
 
-	existingSummary, err := s.Db.GetContentByDocID(context.Background(), respDoc.ID)
+// summarizeFile summarizes a stored upload, or returns the summary already
+// saved for the same range and preprocessing. audioKey is the derived WAV of
+// audio and video files.
+func (s *Service) summarizeFile(respDoc *db.DocumentAudio, audioKey string, opts UploadOptions) (*db.SummaryContent, error) {
+	isDoc := respDoc.FileType == "document" || respDoc.FileType == "image"
+	isVideo := respDoc.FileType == "video"
+	mimeType, objKey := respDoc.MimeType, respDoc.StoragePath
+
+	// books keep their own cache, since they are stored chapter by chapter
+	if mimeType == extract.MimeEPUB {
+		return s.SummarizeBook(context.Background(), respDoc.ID, objKey)
+	}
+
+	rng := contentRange(opts.Range)
+	transcriber := ""
+	if !isDoc {
+		transcriber = opts.Transcriber.cacheKey()
+	}
+	existingSummary, err := s.Db.GetContentByDocID(context.Background(), respDoc.ID, rng, opts.Preprocess.Key(), transcriber)
 	if err == nil && existingSummary != nil {
+		if !isDoc {
+			existingSummary.Segments, err = s.Db.GetTranscriptSegments(context.Background(), existingSummary.Id)
+			return existingSummary, err
+		}
+		existingSummary.Children, err = s.Db.GetChildContents(context.Background(), existingSummary.Id)
+		if err != nil {
+			return nil, err
+		}
+		ocrPages, err := s.Db.GetOCRPages(context.Background(), respDoc.ID)
+		if err != nil {
+			return nil, err
+		}
+		attachOCRPages(existingSummary, ocrPages)
+		existingSummary.TableCount, err = s.Db.CountContentTables(context.Background(), respDoc.ID)
+		if err != nil {
+			return nil, err
+		}
 		return existingSummary, nil
 	}
 
-	fi.Seek(0, io.SeekStart)
-	var content string
-	if utils.IsDoc(fi, fh.Filename) {
-		ext := strings.ToLower(filepath.Ext(fh.Filename))
-		switch ext {
-		case ".pdf":
-			content, err = s.ExtractPdfDoc(objKey)
+	var content, source, summaryText string
+	var segments []db.TranscriptSegment
+	var loudness *LoudnessStats
+	var instructions []string
+	var ocrPages []db.OCRPage
+	var tableCount int
+	if isDoc {
+		source = docSources[mimeType]
+		ctx := extract.WithPDFPassword(context.Background(), opts.PDFPassword)
+		extracted, err := s.ExtractDocument(ctx, objKey, mimeType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract document content: %w", err)
+		}
+		content = extracted.Text()
+		instructions = docInstructions[mimeType]
+
+		if len(extracted.OCRPages) > 0 {
+			for _, p := range extracted.OCRPages {
+				ocrPages = append(ocrPages, db.OCRPage{PageNumber: p.Page, Confidence: p.Confidence, LowQuality: p.LowQuality})
+			}
+			if err := s.Db.SaveOCRPages(context.Background(), respDoc.ID, ocrPages); err != nil {
+				return nil, err
+			}
+			instructions = append(instructions, ocrInstruction)
+		}
+		if tables := extracted.Tables(); len(tables) > 0 {
+			if err := s.saveTables(context.Background(), respDoc.ID, tables); err != nil {
+				return nil, err
+			}
+			instructions = append(instructions, tableInstruction)
+			tableCount = len(tables)
+		}
+		if strings.TrimSpace(content) == "" {
+			return nil, ErrNoText
+		}
+	} else {
+		source = "audio recording"
+		if isVideo {
+			source = "video recording"
+		}
+		if rng != nil {
+			source = fmt.Sprintf("part of a %s (%s)", source, opts.Range)
+			audioKey, err = s.uploadRangeAudio(context.Background(), audioKey, opts.Range)
 			if err != nil {
-				return nil, fmt.Errorf("failed to extract PDF content: %w", err)
+				return nil, fmt.Errorf("failed to trim audio: %w", err)
 			}
-		case ".txt":
-			content, err = s.GetTXTContent(objKey)
+		}
+		if !opts.Preprocess.IsZero() {
+			audioKey, loudness, err = s.preprocessAudio(context.Background(), audioKey, opts.Preprocess)
 			if err != nil {
-				return nil, fmt.Errorf("failed to extract TXT content: %w", err)
+				return nil, fmt.Errorf("failed to preprocess audio: %w", err)
 			}
 		}
+		var used Transcriber
+		segments, summaryText, used, err = s.transcribe(context.Background(), opts.Transcriber, audioKey, opts.Range.Start, source, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to transcribe audio: %w", err)
+		}
+		transcriber = string(used)
+		content = segmentsText(segments)
 	}
 
-	summaryText, err := s.AiGenResponse(context.Background(), content, "pdf document")
-	if err != nil {
-		return nil, err
+	if summaryText == "" {
+		summaryText, err = s.AiGenResponse(context.Background(), content, source, instructions...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !isDoc {
+		return s.Db.CreateMediaContent(context.Background(), db.MediaContent{
+			Content:     content,
+			AiSummary:   summaryText,
+			FileID:      &respDoc.ID,
+			Range:       rng,
+			Segments:    segments,
+			Preprocess:  opts.Preprocess.Key(),
+			Loudness:    loudnessJSON(loudness),
+			Transcriber: transcriber,
+		})
 	}
 
 	sums, err := s.Db.CreateContent(context.Background(), content, summaryText, &respDoc.ID, nil)
 	if err != nil {
 		return nil, err
 	}
+	attachOCRPages(sums, ocrPages)
+	sums.TableCount = tableCount
 
 	return sums, nil
 }
 
-func (s *Service) ExtractPdfDoc(objKey string) (string, error) {
-	buf, err := s.Mc.GetObjectBuffer(mini.DocumentBucket, objKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to get PDF from MinIO: %w", err)
+// uploadKey is where an upload is stored; uploads are keyed by their hash,
+// so the same file is only stored once.
+func uploadKey(hash string, isDoc, isVideo bool) string {
+	switch {
+	case isDoc:
+		return filepath.Join("uploads", "doc", hash)
+	case isVideo:
+		return filepath.Join("uploads", "video", hash)
 	}
+	return filepath.Join("uploads", "audio", hash)
+}
 
-	tmpFile, err := os.CreateTemp("", "pdf-*.pdf")
-	if err != nil {
-		return "", fmt.Errorf("failed to create temp file: %w", err)
-	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
-
-	if _, err := tmpFile.Write(buf.Bytes()); err != nil {
-		return "", fmt.Errorf("failed to write PDF to temp file: %w", err)
+// uploadRangeAudio returns the key of the WAV for part of an upload, cutting
+// it out of the upload's derived WAV the first time the range is asked for.
+func (s *Service) uploadRangeAudio(ctx context.Context, audioKey string, r media.TimeRange) (string, error) {
+	key := strings.TrimSuffix(audioKey, ".wav") + "_" + r.Key() + ".wav"
+	if exists, _ := s.Mc.ObjectExists(mini.DocumentBucket, key); exists {
+		return key, nil
 	}
+	return key, s.trimStoredAudio(ctx, audioKey, key, r)
+}
 
-	cmd := exec.Command("pdftotext", tmpFile.Name(), "-")
-	out, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to extract text: %w", err)
+func (s *Service) saveTables(ctx context.Context, fileID string, tables []extract.TableData) error {
+	rows := make([]db.ContentTable, len(tables))
+	for i, t := range tables {
+		rows[i] = db.ContentTable{Position: i + 1, Rows: t.Rows}
+		if t.Title != "" {
+			rows[i].Title = &t.Title
+		}
+		if t.Page > 0 {
+			rows[i].Page = &t.Page
+		}
 	}
-
-	return string(out), nil
+	return s.Db.ReplaceContentTables(ctx, fileID, rows)
 }
 
-func (s *Service) GetTXTContent(objKey string) (string, error) {
-	buf, err := s.Mc.GetObjectBuffer(mini.DocumentBucket, objKey)
-	if err != nil {
-		return "", err
+// attachOCRPages adds the OCR confidence report to a summary and flags it when
+// any page came out below extract.OCRMinConfidence.
+func attachOCRPages(summ *db.SummaryContent, pages []db.OCRPage) {
+	summ.OCRPages = pages
+	for _, p := range pages {
+		if p.LowQuality {
+			summ.LowQualityOCR = true
+		}
 	}
+}
 
-	b := buf.Bytes()
-	scanner := bufio.NewScanner(bytes.NewReader(b))
+var docSources = map[string]string{
+	extract.MimePDF:      "pdf document",
+	extract.MimeText:     "text document",
+	extract.MimeMarkdown: "markdown document",
+	extract.MimeHTML:     "html page",
+	extract.MimeRTF:      "rtf document",
+	extract.MimeDOCX:     "word document",
+	extract.MimeODT:      "opendocument text",
+	extract.MimeEPUB:     "ebook",
+	extract.MimePPTX:     "slide deck",
+	extract.MimeODP:      "slide deck",
+	extract.MimePNG:      "scanned image",
+	extract.MimeJPEG:     "scanned image",
+	extract.MimeTIFF:     "scanned image",
+	extract.MimeCSV:      "spreadsheet",
+	extract.MimeXLSX:     "spreadsheet",
+}
 
-	const maxCapacity = 20 * 1024 * 1024 // 20mb
-	scanner.Buffer(make([]byte, 1024*1024), maxCapacity)
+var slideInstructions = []string{
+	`Each "## Slide N" heading starts a slide; "Speaker notes:" paragraphs are what the presenter said on that slide.`,
+	`Reference slide numbers in the summary, e.g. "(slide 4)" or "(slides 6-9)", so readers can find the source slide.`,
+}
 
-	var doc strings.Builder
+const tableInstruction = `Markdown tables ("| a | b |") hold tabular data from the file. Report the key figures from them (totals, largest and smallest values, notable changes), with their units and the row/column they come from.`
 
-	doc.Grow(len(b))
+const ocrInstruction = `Some of the text was recognised with OCR and may contain misread words; do not quote text that looks garbled.`
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		doc.WriteString(line)
-		doc.WriteString("\n")
-	}
+var docInstructions = map[string][]string{
+	extract.MimePDF: {
+		`"[Page N]" lines mark where each page of the PDF starts.`,
+		`Cite page numbers for the key points, e.g. "(p. 4)" or "(pp. 6-9)".`,
+	},
+	extract.MimePPTX: slideInstructions,
+	extract.MimeODP:  slideInstructions,
+}
 
-	if err := scanner.Err(); err != nil {
-		return "", err
+func (s *Service) ExtractDocument(ctx context.Context, objKey, mimeType string) (*extract.Document, error) {
+	buf, err := s.Mc.GetObjectBuffer(mini.DocumentBucket, objKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document from MinIO: %w", err)
 	}
 
-	return doc.String(), nil
+	return extract.Extract(ctx, mimeType, buf.Bytes())
 }
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lupppig/briefly/media"
+)
+
+// MaxBatchItems caps how many links and files one batch can hold.
+var MaxBatchItems = 500
+
+var ErrBatchSize = fmt.Errorf("a batch takes between 1 and %d links and files", MaxBatchItems)
+
+// BatchOptions applies to every item of a batch or archive.
+type BatchOptions struct {
+	Title string
+	// Combine adds one summary across all items that succeeded.
+	Combine bool
+	// Transcriber and Preprocess apply to the audio and video items;
+	// documents ignore them.
+	Transcriber Transcriber
+	Preprocess  media.Preprocess
+}
+
+// CheckBatch reports whether a batch of links and file IDs can be submitted.
+func CheckBatch(links, fileIDs []string) error {
+	if n := len(links) + len(fileIDs); n == 0 || n > MaxBatchItems {
+		return ErrBatchSize
+	}
+	return nil
+}
+
+// mediaProviders are the providers a batch link has to match to go through
+// the media pipeline; any other link is summarized as a web page or remote
+// document. The catch-all yt-dlp provider is left out on purpose, since it
+// would claim every article.
+var mediaProviders = []media.SourceProvider{media.YouTube{}, media.Vimeo{}, media.DirectMedia{}}
+
+// ProcessBatchJob runs one child job per link and per previously uploaded
+// file and groups the results under a collection. Items that fail are
+// reported but do not fail the batch; it only fails when no item could be
+// summarized. With opts.Combine the collection also gets a combined summary.
+func (s *Service) ProcessBatchJob(jobID string, links, fileIDs []string, opts BatchOptions) {
+	update := func(status string, summary interface{}, errMsg string) {
+		s.JobManager.UpdateJob(jobID, status, summary, errMsg)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			update("error", "", fmt.Sprintf("panic: %v", r))
+		}
+	}()
+
+	ctx := context.Background()
+	mediaOpts := MediaOptions{Transcriber: opts.Transcriber, Preprocess: opts.Preprocess}
+	uploadOpts := UploadOptions{Transcriber: opts.Transcriber, Preprocess: opts.Preprocess}
+
+	var tasks []childTask
+	for _, link := range links {
+		task := childTask{Link: link}
+		if _, _, err := media.ResolveSource(link, mediaProviders...); err == nil {
+			task.run = func(id string) { s.ProcessMediaJob(id, link, mediaOpts) }
+		} else {
+			task.run = func(id string) { s.ProcessURLJob(id, link) }
+		}
+		tasks = append(tasks, task)
+	}
+	for _, fileID := range fileIDs {
+		task := childTask{FileID: fileID, run: func(id string) { s.ProcessFileJob(id, fileID, uploadOpts) }}
+		if doc, _ := s.Db.GetDocument(ctx, fileID); doc != nil {
+			task.Title = doc.Name
+		}
+		tasks = append(tasks, task)
+	}
+
+	coll, err := s.Db.CreateCollection(ctx, "batch", "batch", opts.Title)
+	if err != nil {
+		update("error", "", "failed db fetch")
+		return
+	}
+	result := &CollectionResult{Collection: coll}
+
+	update("processing_items", "", "")
+	result.Items = s.runChildJobs(jobID, tasks)
+
+	if opts.Combine {
+		update("summarizing", "", "")
+	}
+	source := fmt.Sprintf("the summaries of a batch of %d items", len(tasks))
+	if opts.Title != "" {
+		source = fmt.Sprintf("%s titled %q", source, opts.Title)
+	}
+	if err := s.groupCollection(ctx, result, opts.Combine, source); err != nil {
+		update("error", result, err.Error())
+		return
+	}
+
+	update("done", result, "")
+}
+
+// ProcessFileJob summarizes a previously uploaded file as a background job.
+// Preprocessing is dropped for documents, so one set of options can cover a
+// mix of files.
+func (s *Service) ProcessFileJob(jobID, fileID string, opts UploadOptions) {
+	update := func(status string, summary interface{}, errMsg string) {
+		s.JobManager.UpdateJob(jobID, status, summary, errMsg)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			update("error", "", fmt.Sprintf("panic: %v", r))
+		}
+	}()
+
+	update("processing", "", "")
+	summ, err := s.SummarizeStoredFile(fileID, opts)
+	if errors.Is(err, ErrPreprocessDoc) {
+		opts.Preprocess = media.Preprocess{}
+		summ, err = s.SummarizeStoredFile(fileID, opts)
+	}
+	if err != nil {
+		update("error", "", err.Error())
+		return
+	}
+	update("done", summ, "")
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/lupppig/briefly/db/mini"
+	db "github.com/lupppig/briefly/db/postgres"
+	"github.com/lupppig/briefly/extract"
+)
+
+// bookLocks keeps two jobs from summarizing the same book at once; the second
+// one waits and finds the finished summary. An entry is removed once nobody
+// holds or waits for it, so the map only holds books being summarized.
+var (
+	bookLocksMu sync.Mutex
+	bookLocks   = map[string]*bookLock{}
+)
+
+type bookLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lockBook locks fileID and returns the function that unlocks it.
+func lockBook(fileID string) func() {
+	bookLocksMu.Lock()
+	l, ok := bookLocks[fileID]
+	if !ok {
+		l = &bookLock{}
+		bookLocks[fileID] = l
+	}
+	l.refs++
+	bookLocksMu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		bookLocksMu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(bookLocks, fileID)
+		}
+		bookLocksMu.Unlock()
+	}
+}
+
+// SummarizeBook summarizes every chapter of an EPUB on its own and then builds
+// the whole-book summary from the chapter summaries, so long books never have
+// to fit into a single prompt. The book is stored as a parent content row with
+// one child row per chapter. Each chapter is stored as soon as it is
+// summarized, and the parent only gets its summary at the end, so a book that
+// failed halfway resumes from the chapters already done.
+func (s *Service) SummarizeBook(ctx context.Context, fileID, objKey string) (*db.SummaryContent, error) {
+	defer lockBook(fileID)()
+
+	parent, err := s.Db.GetContentByDocID(ctx, fileID, nil, "", "")
+	if err != nil {
+		return nil, err
+	}
+	if parent != nil && parent.AiSummary != "" {
+		parent.Children, err = s.Db.GetChildContents(ctx, parent.Id)
+		return parent, err
+	}
+
+	buf, err := s.Mc.GetObjectBuffer(mini.DocumentBucket, objKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get epub from MinIO: %w", err)
+	}
+
+	book, err := extract.ParseEPUB(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse epub: %w", err)
+	}
+
+	bookName := book.Title
+	if bookName == "" {
+		bookName = "an untitled book"
+	}
+
+	if parent == nil {
+		parent, err = s.Db.CreateContent(ctx, "", "", &fileID, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	done, err := s.Db.GetChildContents(ctx, parent.Id)
+	if err != nil {
+		return nil, err
+	}
+	stored := map[int]db.SummaryContent{}
+	for _, c := range done {
+		if c.Position != nil {
+			stored[*c.Position] = c
+		}
+	}
+
+	var fullText, digest strings.Builder
+	for i, ch := range book.Chapters {
+		text := ch.Document.Text()
+		child, ok := stored[i+1]
+		if !ok {
+			source := fmt.Sprintf("chapter %d (%q) of the book %q", i+1, ch.Title, bookName)
+			summary, err := s.AiGenResponse(ctx, text, source)
+			if err != nil {
+				return nil, fmt.Errorf("failed to summarize chapter %d: %w", i+1, err)
+			}
+			c, err := s.Db.CreateChildContent(ctx, parent, ch.Title, i+1, text, summary)
+			if err != nil {
+				return nil, err
+			}
+			child = *c
+		}
+		parent.Children = append(parent.Children, child)
+
+		fmt.Fprintf(&fullText, "# %s\n\n%s\n\n", ch.Title, text)
+		fmt.Fprintf(&digest, "# Chapter %d: %s\n\n%s\n\n", i+1, ch.Title, child.AiSummary)
+	}
+
+	source := fmt.Sprintf("the chapter-by-chapter summaries of the book %q", bookName)
+	if book.Author != "" {
+		source += " by " + book.Author
+	}
+	bookSummary, err := s.AiGenResponse(ctx, digest.String(), source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize book: %w", err)
+	}
+
+	parent.Content = strings.TrimSpace(fullText.String())
+	parent.AiSummary = bookSummary
+	if err := s.Db.UpdateContent(ctx, parent.Id, parent.Content, parent.AiSummary); err != nil {
+		return nil, err
+	}
+	return parent, nil
+}
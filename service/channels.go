@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	db "github.com/lupppig/briefly/db/postgres"
+	"github.com/lupppig/briefly/utils"
+)
+
+var (
+	// ChannelWatchInterval is how often a channel is checked when the watch
+	// does not ask for an interval.
+	ChannelWatchInterval = time.Hour
+	// MinChannelWatchInterval keeps watches from listing channels so often
+	// that YouTube starts throttling yt-dlp.
+	MinChannelWatchInterval = 15 * time.Minute
+	// ChannelWatchTick is how often the watcher looks for watches that are
+	// due; it bounds how late a check can run.
+	ChannelWatchTick = time.Minute
+	// ChannelListingSize is how many of the latest uploads each check lists.
+	// Uploads that scroll past it between two checks are missed, so it should
+	// cover what a channel publishes in one interval.
+	ChannelListingSize = 15
+	// MaxChannelBackfill caps how many existing uploads can be queued when a
+	// channel is watched.
+	MaxChannelBackfill = 10
+)
+
+var (
+	ErrNotChannel      = errors.New("only youtube channel links can be watched")
+	ErrWatchInterval   = fmt.Errorf("interval must be at least %s", MinChannelWatchInterval)
+	ErrChannelBackfill = fmt.Errorf("backfill must be between 0 and %d", MaxChannelBackfill)
+)
+
+// watchMu serializes channel checks and video processing, so a video is never
+// picked up twice and transcription does not pile up.
+var watchMu sync.Mutex
+
+// AddChannelWatch starts watching a YouTube channel. Uploads already on the
+// channel are recorded but skipped, except for the latest backfill ones,
+// which are queued; after that only new uploads are summarized. Watching a
+// channel that is already watched returns the existing watch unchanged.
+func (s *Service) AddChannelWatch(ctx context.Context, link string, interval time.Duration, backfill int) (*db.ChannelWatch, error) {
+	if backfill < 0 || backfill > MaxChannelBackfill {
+		return nil, ErrChannelBackfill
+	}
+	if interval == 0 {
+		interval = ChannelWatchInterval
+	}
+	if interval < MinChannelWatchInterval {
+		return nil, ErrWatchInterval
+	}
+
+	yc, err := utils.ParseYouTubeCollection(link)
+	if err != nil {
+		return nil, err
+	}
+	if yc.Kind != utils.CollectionChannel {
+		return nil, ErrNotChannel
+	}
+
+	listing, err := listYouTubeCollection(ctx, yc, ChannelListingSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// the listing is newest first
+	videos := watchVideos(listing.Entries, db.ItemSkipped)
+	for i := 0; i < backfill && i < len(videos); i++ {
+		videos[len(videos)-1-i].Status = db.ItemPending
+	}
+	cw, created, err := s.Db.CreateChannelWatch(ctx, yc.ID, yc.URL, listing.Title, int(interval/time.Second), videos)
+	if err != nil || !created {
+		return cw, err
+	}
+
+	if backfill > 0 {
+		go func() {
+			watchMu.Lock()
+			defer watchMu.Unlock()
+			s.processWatchVideos(context.Background(), cw)
+		}()
+	}
+	return cw, nil
+}
+
+// StartChannelWatcher checks every due channel now and then every
+// ChannelWatchTick until ctx is done. Videos interrupted by a restart are
+// queued again first.
+func (s *Service) StartChannelWatcher(ctx context.Context) {
+	if err := s.Db.RequeueProcessingWatchVideos(ctx); err != nil {
+		log.Printf("failed to requeue channel videos: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(ChannelWatchTick)
+		defer ticker.Stop()
+		for {
+			s.CheckChannels(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CheckChannels lists every channel whose interval has passed and summarizes
+// its new uploads.
+func (s *Service) CheckChannels(ctx context.Context) {
+	watchMu.Lock()
+	defer watchMu.Unlock()
+
+	watches, err := s.Db.DueChannelWatches(ctx)
+	if err != nil {
+		log.Printf("failed to list channel watches: %v", err)
+		return
+	}
+	for i := range watches {
+		if ctx.Err() != nil {
+			return
+		}
+		cw := &watches[i]
+		if err := s.checkChannel(ctx, cw); err != nil {
+			log.Printf("failed to check channel %s: %v", cw.URL, err)
+			s.Db.UpdateChannelWatchCheck(ctx, cw.ID, "", err.Error())
+		}
+		s.processWatchVideos(ctx, cw)
+	}
+}
+
+func (s *Service) checkChannel(ctx context.Context, cw *db.ChannelWatch) error {
+	listing, err := listYouTubeCollection(ctx, &utils.YouTubeCollection{
+		Kind: utils.CollectionChannel,
+		ID:   cw.ChannelKey,
+		URL:  cw.URL,
+	}, ChannelListingSize)
+	if err != nil {
+		return err
+	}
+
+	added, err := s.Db.InsertWatchVideos(ctx, cw.ID, watchVideos(listing.Entries, db.ItemPending))
+	if err != nil {
+		return err
+	}
+	if added > 0 {
+		log.Printf("channel %s: %d new videos", cw.URL, added)
+	}
+	return s.Db.UpdateChannelWatchCheck(ctx, cw.ID, listing.Title, "")
+}
+
+// processWatchVideos runs every queued video of a channel through the
+// YouTube pipeline, one at a time. Callers hold watchMu.
+func (s *Service) processWatchVideos(ctx context.Context, cw *db.ChannelWatch) {
+	videos, err := s.Db.PendingWatchVideos(ctx, cw.ID)
+	if err != nil {
+		log.Printf("failed to list queued videos of %s: %v", cw.URL, err)
+		return
+	}
+
+	for _, v := range videos {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.Db.UpdateWatchVideoStatus(ctx, v.ID, db.ItemProcessing, "", nil); err != nil {
+			log.Printf("failed to update channel video %s: %v", v.ID, err)
+			continue
+		}
+
+		jobID := utils.NewJobID()
+		s.JobManager.CreateJob(jobID)
+		s.ProcessYoutubeJob(jobID, "https://www.youtube.com/watch?v="+v.VideoID, MediaOptions{})
+
+		job, _ := s.JobManager.Snapshot(jobID)
+		if summ := jobSummary(job); summ != nil {
+			s.Db.UpdateWatchVideoStatus(ctx, v.ID, db.ItemDone, "", &summ.Id)
+		} else {
+			s.Db.UpdateWatchVideoStatus(ctx, v.ID, db.ItemError, job.Error, nil)
+		}
+	}
+}
+
+// watchVideos turns a newest-first listing into rows in upload order, leaving
+// out entries that cannot be fetched yet. Upcoming streams are left out
+// rather than skipped, so they are picked up once the recording exists.
+func watchVideos(entries []ytEntry, status string) []db.WatchVideo {
+	out := make([]db.WatchVideo, 0, len(entries))
+	for _, e := range slices.Backward(entries) {
+		if !e.fetchable() {
+			continue
+		}
+		v := db.WatchVideo{VideoID: e.ID, Status: status}
+		if e.Title != "" {
+			title := e.Title
+			v.Title = &title
+		}
+		out = append(out, v)
+	}
+	return out
+}
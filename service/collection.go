@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	db "github.com/lupppig/briefly/db/postgres"
+)
+
+// CollectionWorkers is how many child jobs of one collection run at once.
+// Transcription is CPU bound, so this stays small.
+var CollectionWorkers = 2
+
+// childTask is one item of a collection; run processes it under its own job
+// ID, the same way a standalone request would.
+type childTask struct {
+	Title  string
+	Link   string
+	FileID string
+	run    func(jobID string)
+}
+
+// ChildResult is the outcome of one child job, as reported by its parent.
+type ChildResult struct {
+	JobID   string             `json:"job_id"`
+	Title   string             `json:"title,omitempty"`
+	Link    string             `json:"link,omitempty"`
+	FileID  string             `json:"file_id,omitempty"`
+	Status  string             `json:"status"`
+	Error   string             `json:"error,omitempty"`
+	Summary *db.SummaryContent `json:"summary,omitempty"`
+}
+
+// CollectionResult is the summary of a finished parent job.
+type CollectionResult struct {
+	Collection *db.Collection     `json:"collection"`
+	Digest     *db.SummaryContent `json:"digest,omitempty"`
+	Items      []ChildResult      `json:"items"`
+	// Skipped lists items that were left out before processing, such as
+	// unsupported files in an archive.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// runChildJobs registers one child job per task under parentID, runs them
+// with CollectionWorkers workers and keeps the parent's progress up to date.
+// Results come back in task order.
+func (s *Service) runChildJobs(parentID string, tasks []childTask) []ChildResult {
+	results := make([]ChildResult, len(tasks))
+	for i, t := range tasks {
+		id := s.JobManager.NewChildJob(parentID)
+		results[i] = ChildResult{JobID: id, Title: t.Title, Link: t.Link, FileID: t.FileID, Status: "pending"}
+	}
+
+	var (
+		mu       sync.Mutex
+		progress = JobProgress{Total: len(tasks)}
+		wg       sync.WaitGroup
+		next     = make(chan int)
+	)
+	s.JobManager.SetProgress(parentID, progress)
+
+	for w := 0; w < max(1, CollectionWorkers); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				res := &results[i]
+				tasks[i].run(res.JobID)
+
+				job, _ := s.JobManager.Snapshot(res.JobID)
+				res.Status, res.Error = job.Status, job.Error
+				res.Summary = jobSummary(job)
+
+				mu.Lock()
+				if res.Summary != nil {
+					progress.Done++
+				} else {
+					progress.Failed++
+				}
+				s.JobManager.SetProgress(parentID, progress)
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range tasks {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
+
+// name is how the item is referred to in a combined summary.
+func (r ChildResult) name() string {
+	switch {
+	case r.Title != "":
+		return r.Title
+	case r.Link != "":
+		return r.Link
+	}
+	return r.FileID
+}
+
+// jobSummary returns the stored content of a finished job, or nil if the job
+// did not succeed.
+func jobSummary(job JobStatus) *db.SummaryContent {
+	if job.Status != "done" && job.Status != "cached_summary_found" {
+		return nil
+	}
+	summ, _ := job.Summary.(*db.SummaryContent)
+	return summ
+}
+
+// groupCollection lists the children that succeeded under the collection
+// and, with combine, adds the combined summary. It fails when no child
+// succeeded.
+func (s *Service) groupCollection(ctx context.Context, result *CollectionResult, combine bool, source string) error {
+	coll := result.Collection
+	var contentIDs []string
+	for _, r := range result.Items {
+		if r.Summary != nil {
+			contentIDs = append(contentIDs, r.Summary.Id)
+		}
+	}
+	if len(contentIDs) == 0 {
+		return fmt.Errorf("no item of the %s could be summarized", coll.Kind)
+	}
+	if err := s.Db.AddCollectionItems(ctx, coll.ID, contentIDs); err != nil {
+		return errors.New("failed to save collection")
+	}
+	if !combine {
+		return nil
+	}
+
+	digest, err := s.summarizeCollection(ctx, coll, result.Items, source)
+	if err != nil {
+		return err
+	}
+	result.Digest = digest
+	return nil
+}
+
+// summarizeCollection builds the combined summary from the summaries of the
+// children that succeeded, the same way a book summary is built from its
+// chapter summaries, and stores it against the collection.
+func (s *Service) summarizeCollection(ctx context.Context, coll *db.Collection, results []ChildResult, source string) (*db.SummaryContent, error) {
+	var (
+		digest     strings.Builder
+		contentIDs []string
+	)
+	n := 0
+	for _, r := range results {
+		if r.Summary == nil {
+			continue
+		}
+		n++
+		fmt.Fprintf(&digest, "# %d. %s\n\n%s\n\n", n, r.name(), r.Summary.AiSummary)
+		contentIDs = append(contentIDs, r.Summary.Id)
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("no item of the %s could be summarized", coll.Kind)
+	}
+
+	text := strings.TrimSpace(digest.String())
+	summary, err := s.AiGenResponse(ctx, text, source,
+		"Describe what the items have in common and how they relate before covering what is specific to each of them.")
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize %s: %w", coll.Kind, err)
+	}
+
+	return s.Db.SaveCollectionDigest(ctx, coll.ID, contentIDs, text, summary)
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	db "github.com/lupppig/briefly/db/postgres"
+	"github.com/lupppig/briefly/feed"
+	"github.com/lupppig/briefly/media"
+	"github.com/lupppig/briefly/utils"
+)
+
+var (
+	// FeedPollInterval is how often every registered feed is checked for new
+	// episodes.
+	FeedPollInterval = time.Hour
+	// MaxFeedBackfill caps how many existing episodes can be queued when a
+	// feed is registered.
+	MaxFeedBackfill = 20
+)
+
+var ErrFeedBackfill = fmt.Errorf("backfill must be between 0 and %d", MaxFeedBackfill)
+
+// feedMu serializes feed polling and episode processing, so an episode is
+// never picked up twice and transcription does not pile up.
+var feedMu sync.Mutex
+
+// AddFeed registers a podcast feed. Episodes already in the feed are recorded
+// but skipped, except for the latest backfill ones, which are queued; after
+// that only new episodes are summarized. Registering a known feed returns it
+// unchanged.
+func (s *Service) AddFeed(ctx context.Context, link string, backfill int) (*db.Feed, error) {
+	if backfill < 0 || backfill > MaxFeedBackfill {
+		return nil, ErrFeedBackfill
+	}
+	normalized, err := utils.NormalizeURL(link)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := fetchURL(ctx, normalized, "")
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := feed.Parse(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// parsed items are newest first
+	items := feedItems(parsed.Items, db.ItemSkipped)
+	for i := 0; i < backfill && i < len(items); i++ {
+		items[i].Status = db.ItemPending
+	}
+	f, created, err := s.Db.CreateFeed(ctx, normalized, parsed.Title, parsed.Link, res.ETag, items)
+	if err != nil || !created {
+		return f, err
+	}
+
+	if backfill > 0 {
+		go func() {
+			feedMu.Lock()
+			defer feedMu.Unlock()
+			s.processFeedItems(context.Background(), f)
+		}()
+	}
+	return f, nil
+}
+
+// StartFeedPoller polls every feed now and then every interval until ctx is
+// done. Episodes interrupted by a restart are queued again first.
+func (s *Service) StartFeedPoller(ctx context.Context, interval time.Duration) {
+	if err := s.Db.RequeueProcessingFeedItems(ctx); err != nil {
+		log.Printf("failed to requeue feed items: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.PollFeeds(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// PollFeeds checks every feed for new episodes and summarizes whatever is
+// queued.
+func (s *Service) PollFeeds(ctx context.Context) {
+	feedMu.Lock()
+	defer feedMu.Unlock()
+
+	feeds, err := s.Db.ListFeeds(ctx)
+	if err != nil {
+		log.Printf("failed to list feeds: %v", err)
+		return
+	}
+	for i := range feeds {
+		if ctx.Err() != nil {
+			return
+		}
+		f := &feeds[i]
+		if err := s.pollFeed(ctx, f); err != nil {
+			log.Printf("failed to poll feed %s: %v", f.URL, err)
+			s.Db.UpdateFeedPoll(ctx, f.ID, "", "", err.Error())
+		}
+		s.processFeedItems(ctx, f)
+	}
+}
+
+func (s *Service) pollFeed(ctx context.Context, f *db.Feed) error {
+	res, err := fetchURL(ctx, f.URL, derefOr(f.ETag, ""))
+	if err != nil {
+		return err
+	}
+	if res.NotModified {
+		return s.Db.UpdateFeedPoll(ctx, f.ID, "", "", "")
+	}
+
+	parsed, err := feed.Parse(res.Body)
+	if err != nil {
+		return err
+	}
+	added, err := s.Db.InsertFeedItems(ctx, f.ID, feedItems(parsed.Items, db.ItemPending))
+	if err != nil {
+		return err
+	}
+	if added > 0 {
+		log.Printf("feed %s: %d new episodes", f.URL, added)
+	}
+	return s.Db.UpdateFeedPoll(ctx, f.ID, parsed.Title, res.ETag, "")
+}
+
+// processFeedItems runs every queued episode of a feed through the media
+// pipeline, one at a time. Callers hold feedMu.
+func (s *Service) processFeedItems(ctx context.Context, f *db.Feed) {
+	items, err := s.Db.PendingFeedItems(ctx, f.ID)
+	if err != nil {
+		log.Printf("failed to list queued episodes of %s: %v", f.URL, err)
+		return
+	}
+
+	feedTitle := derefOr(f.Title, f.URL)
+	for _, it := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.Db.UpdateFeedItemStatus(ctx, it.ID, db.ItemProcessing, "", nil); err != nil {
+			log.Printf("failed to update episode %s: %v", it.ID, err)
+			continue
+		}
+
+		jobID := utils.NewJobID()
+		s.JobManager.CreateJob(jobID)
+		opts := MediaOptions{Title: fmt.Sprintf("%s (%s)", derefOr(it.Title, "untitled episode"), feedTitle)}
+		s.processSource(jobID, it.EnclosureURL, opts, media.DirectMedia{}, media.YTDLP{})
+
+		job, _ := s.JobManager.Snapshot(jobID)
+		if summ := jobSummary(job); summ != nil {
+			s.Db.UpdateFeedItemStatus(ctx, it.ID, db.ItemDone, "", &summ.Id)
+		} else {
+			s.Db.UpdateFeedItemStatus(ctx, it.ID, db.ItemError, job.Error, nil)
+		}
+	}
+}
+
+func feedItems(items []feed.Item, status string) []db.FeedItem {
+	out := make([]db.FeedItem, 0, len(items))
+	for _, it := range items {
+		fi := db.FeedItem{GUID: it.GUID, EnclosureURL: it.EnclosureURL, Status: status}
+		if it.Title != "" {
+			fi.Title = &it.Title
+		}
+		if it.Link != "" {
+			fi.Link = &it.Link
+		}
+		if !it.Published.IsZero() {
+			published := it.Published
+			fi.PublishedAt = &published
+		}
+		out = append(out, fi)
+	}
+	return out
+}
+
+// IsFeedError reports whether err means the URL is not a usable feed rather
+// than a failure on our side.
+func IsFeedError(err error) bool {
+	return errors.Is(err, feed.ErrNotFeed) || errors.Is(err, ErrURLTooLarge)
+}
@@ -1,13 +1,39 @@
 package service
 
 import (
+	"context"
 	"sync"
+	"time"
+
+	"github.com/lupppig/briefly/utils"
 )
 
+// JobTTL is how long a finished job can still be polled before it is
+// evicted. Results are stored in the database; jobs only report progress.
+var JobTTL = time.Hour
+
 type JobStatus struct {
 	Status  string      `json:"status"`
 	Summary interface{} `json:"summary,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	// Children and Progress are set on jobs that fan out into one child job
+	// per item, such as playlists.
+	Children []string     `json:"children,omitempty"`
+	Progress *JobProgress `json:"progress,omitempty"`
+
+	parent   string
+	finished time.Time
+}
+
+// jobFinished reports whether status is one a job ends in.
+func jobFinished(status string) bool {
+	return status == "done" || status == "error" || status == "cached_summary_found"
+}
+
+type JobProgress struct {
+	Total  int `json:"total"`
+	Done   int `json:"done"`
+	Failed int `json:"failed"`
 }
 
 type JobManager struct {
@@ -32,6 +58,10 @@ func (jm *JobManager) UpdateJob(jobID string, status string, summary interface{}
 	defer jm.mu.Unlock()
 	if job, ok := jm.jobs[jobID]; ok {
 		job.Status = status
+		job.finished = time.Time{}
+		if jobFinished(status) {
+			job.finished = time.Now()
+		}
 		if summary != "" {
 			job.Summary = summary
 		}
@@ -46,3 +76,81 @@ func (jm *JobManager) GetJob(jobID string) *JobStatus {
 	defer jm.mu.RUnlock()
 	return jm.jobs[jobID]
 }
+
+// Snapshot returns a copy of a job that is safe to read while the job keeps
+// running.
+func (jm *JobManager) Snapshot(jobID string) (JobStatus, bool) {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	job, ok := jm.jobs[jobID]
+	if !ok {
+		return JobStatus{}, false
+	}
+	cp := *job
+	cp.Children = append([]string(nil), job.Children...)
+	if job.Progress != nil {
+		p := *job.Progress
+		cp.Progress = &p
+	}
+	return cp, true
+}
+
+// NewChildJob creates a pending job and lists it under parentID.
+func (jm *JobManager) NewChildJob(parentID string) string {
+	childID := utils.NewJobID()
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	child := &JobStatus{Status: "pending"}
+	if job, ok := jm.jobs[parentID]; ok {
+		job.Children = append(job.Children, childID)
+		child.parent = parentID
+	}
+	jm.jobs[childID] = child
+	return childID
+}
+
+func (jm *JobManager) SetProgress(jobID string, progress JobProgress) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	if job, ok := jm.jobs[jobID]; ok {
+		job.Progress = &progress
+	}
+}
+
+// Evict removes the jobs that finished before cutoff. Child jobs are removed
+// along with their parent, never before it, so a running batch can still
+// report every item.
+func (jm *JobManager) Evict(cutoff time.Time) int {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	n := 0
+	for id, job := range jm.jobs {
+		if job.parent != "" || job.finished.IsZero() || !job.finished.Before(cutoff) {
+			continue
+		}
+		for _, child := range job.Children {
+			delete(jm.jobs, child)
+			n++
+		}
+		delete(jm.jobs, id)
+		n++
+	}
+	return n
+}
+
+// StartEviction evicts jobs that finished more than ttl ago, checking every
+// ttl/4 until ctx is done.
+func (jm *JobManager) StartEviction(ctx context.Context, ttl time.Duration) {
+	go func() {
+		ticker := time.NewTicker(max(ttl/4, time.Minute))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				jm.Evict(time.Now().Add(-ttl))
+			}
+		}
+	}()
+}
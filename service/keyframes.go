@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/lupppig/briefly/db/mini"
+	db "github.com/lupppig/briefly/db/postgres"
+	"github.com/lupppig/briefly/media"
+)
+
+// attachKeyframes builds content's timeline from its keyframes, extracting
+// them from the source video the first time. With caption set, keyframes
+// without a caption are described by the multimodal model.
+func (s *Service) attachKeyframes(ctx context.Context, p media.SourceProvider, src *media.Source, ms *db.MediaSource, content *db.SummaryContent, caption bool) error {
+	frames, err := s.Db.GetKeyframes(ctx, content.Id)
+	if err != nil {
+		return err
+	}
+	if len(frames) == 0 {
+		if frames, err = s.extractKeyframes(ctx, p, src, ms, content.Id); err != nil {
+			return err
+		}
+	}
+
+	timeline := buildTimeline(frames, content.Segments)
+	if caption {
+		for i := range frames {
+			if frames[i].Caption != nil {
+				continue
+			}
+			if err := s.captionKeyframe(ctx, &frames[i], timeline[i].Text); err != nil {
+				log.Printf("failed to caption keyframe %s: %v", frames[i].ID, err)
+				continue
+			}
+			timeline[i].Caption = frames[i].Caption
+		}
+	}
+
+	content.Timeline = timeline
+	return nil
+}
+
+// extractKeyframes downloads the video of src, takes a still at each scene
+// change and stores them under the source's prefix in MinIO.
+func (s *Service) extractKeyframes(ctx context.Context, p media.SourceProvider, src *media.Source, ms *db.MediaSource, contentID string) ([]db.Keyframe, error) {
+	vf, ok := p.(media.VideoFetcher)
+	if !ok {
+		return nil, media.ErrNoVideo
+	}
+
+	dir, err := os.MkdirTemp("", "keyframes-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	videoPath, err := vf.FetchVideo(ctx, src, dir)
+	if err != nil {
+		return nil, err
+	}
+	extracted, err := media.ExtractKeyframes(ctx, videoPath, dir, src.Range.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := make([]db.Keyframe, 0, len(extracted))
+	for _, kf := range extracted {
+		key := fmt.Sprintf("%s_keyframes/%s/%s/%d.jpg", src.Provider, ms.ID, contentID, int64(math.Round(kf.Time*1000)))
+		if err := s.putLocalFile(ctx, mini.DocumentBucket, key, kf.Path, "image/jpeg"); err != nil {
+			return nil, err
+		}
+		frames = append(frames, db.Keyframe{Time: kf.Time, ObjectKey: key})
+	}
+	return s.Db.SaveKeyframes(ctx, contentID, frames)
+}
+
+func (s *Service) captionKeyframe(ctx context.Context, kf *db.Keyframe, narration string) error {
+	buf, err := s.Mc.GetObjectBuffer(mini.DocumentBucket, kf.ObjectKey)
+	if err != nil {
+		return fmt.Errorf("failed to read keyframe from MinIO: %w", err)
+	}
+	caption, err := s.AiDescribeFrame(ctx, buf.Bytes(), narration)
+	if err != nil {
+		return err
+	}
+	if err := s.Db.UpdateKeyframeCaption(ctx, kf.ID, caption); err != nil {
+		return err
+	}
+	kf.Caption = &caption
+	return nil
+}
+
+// buildTimeline gives each keyframe the transcript segments that start while
+// it is on screen, up to the next keyframe. Segments before the first
+// keyframe go to the first one.
+func buildTimeline(frames []db.Keyframe, segs []db.TranscriptSegment) []db.TimelineEntry {
+	timeline := make([]db.TimelineEntry, len(frames))
+	for i, f := range frames {
+		timeline[i] = db.TimelineEntry{
+			Start:    f.Time,
+			ImageURL: "/api/keyframes/" + f.ID,
+			Caption:  f.Caption,
+		}
+		if i+1 < len(frames) {
+			timeline[i].End = frames[i+1].Time
+		} else if len(segs) > 0 {
+			timeline[i].End = math.Max(f.Time, segs[len(segs)-1].End)
+		}
+	}
+
+	texts := make([]strings.Builder, len(frames))
+	j := 0
+	for _, seg := range segs {
+		for j+1 < len(frames) && seg.Start >= frames[j+1].Time {
+			j++
+		}
+		if j >= len(frames) {
+			break
+		}
+		if texts[j].Len() > 0 {
+			texts[j].WriteByte(' ')
+		}
+		texts[j].WriteString(strings.TrimSpace(seg.Text))
+	}
+	for i := range timeline {
+		timeline[i].Text = texts[i].String()
+	}
+	return timeline
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+	db "github.com/lupppig/briefly/db/postgres"
+	"github.com/lupppig/briefly/media"
+)
+
+var (
+	// LiveStep is how often a live session runs whisper over the audio it has
+	// not committed yet.
+	LiveStep = 2 * time.Second
+	// LiveMaxWindow is the most audio transcribed in one step. Audio that
+	// reaches it is committed whole, which bounds both the latency and the
+	// cost of each step when speech runs on without a break.
+	LiveMaxWindow = 30 * time.Second
+	// LiveSummaryInterval is how often a rolling summary is pushed when the
+	// session does not ask for another interval.
+	LiveSummaryInterval = 5 * time.Minute
+	// MaxLiveSession caps how long one live session can stream.
+	MaxLiveSession = 4 * time.Hour
+	// MaxLiveBacklog caps the audio waiting to be transcribed. It only fills
+	// up when whisper runs slower than real time or the client streams
+	// faster than that; the session is ended once it is full.
+	MaxLiveBacklog = 5 * time.Minute
+)
+
+var (
+	ErrEmptySession = errors.New("no speech was transcribed in the session")
+	ErrLiveBacklog  = errors.New("transcription fell too far behind the audio stream")
+)
+
+// Live event types, sent to the client as the "type" field.
+const (
+	LivePartial = "partial"
+	LiveFinal   = "final"
+	LiveSummary = "summary"
+	LiveDone    = "done"
+	LiveError   = "error"
+)
+
+// LiveEvent is pushed to the client of a live session. Partial events carry
+// the uncommitted tail of the transcript, which the next partial or final
+// event replaces; final segments never change.
+type LiveEvent struct {
+	Type     string                 `json:"type"`
+	Segment  *db.TranscriptSegment  `json:"segment,omitempty"`
+	Segments []db.TranscriptSegment `json:"segments,omitempty"`
+	Summary  string                 `json:"summary,omitempty"`
+	Content  *db.SummaryContent     `json:"content,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// LiveOptions are the per-session settings.
+type LiveOptions struct {
+	Format media.StreamFormat
+	Title  string
+	// SummaryEvery is the rolling summary interval; zero uses
+	// LiveSummaryInterval.
+	SummaryEvery time.Duration
+}
+
+// LiveSession transcribes a stream of audio frames as they arrive. Audio is
+// kept until whisper commits it: every LiveStep the uncommitted audio is
+// transcribed, all segments but the last are final, and the last one, which
+// may still be cut mid-word, is only sent as a partial.
+type LiveSession struct {
+	s      *Service
+	opts   LiveOptions
+	send   func(LiveEvent)
+	stream *media.AudioStream
+
+	mu           sync.Mutex
+	pending      []float32
+	pendingStart time.Duration
+	final        []db.TranscriptSegment
+	// behind is set once pending reached MaxLiveBacklog; later audio is
+	// dropped and Write fails.
+	behind bool
+
+	stop    chan struct{}
+	drained chan struct{}
+	loop    sync.WaitGroup
+}
+
+// StartLiveSession starts decoding and transcribing. send is called from the
+// session's own goroutines, one call at a time.
+func (s *Service) StartLiveSession(ctx context.Context, opts LiveOptions, send func(LiveEvent)) (*LiveSession, error) {
+	if opts.SummaryEvery == 0 {
+		opts.SummaryEvery = LiveSummaryInterval
+	}
+	stream, err := media.NewAudioStream(ctx, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	ls := &LiveSession{
+		s:       s,
+		opts:    opts,
+		stream:  stream,
+		stop:    make(chan struct{}),
+		drained: make(chan struct{}),
+	}
+	var sendMu sync.Mutex
+	ls.send = func(e LiveEvent) {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		send(e)
+	}
+
+	go ls.drain()
+	ls.loop.Add(1)
+	go ls.run(ctx)
+	return ls, nil
+}
+
+// Send pushes an event to the client through the same serialized sender the
+// session uses, so callers never write to the connection concurrently with
+// the session's goroutines.
+func (ls *LiveSession) Send(e LiveEvent) {
+	ls.send(e)
+}
+
+// Write feeds one audio frame. It fails with ErrLiveBacklog once the
+// session can no longer keep up.
+func (ls *LiveSession) Write(frame []byte) error {
+	ls.mu.Lock()
+	behind := ls.behind
+	ls.mu.Unlock()
+	if behind {
+		return ErrLiveBacklog
+	}
+	_, err := ls.stream.Write(frame)
+	return err
+}
+
+// drain moves decoded samples into the pending buffer until the stream ends.
+// Once the buffer is full the stream is still read, so the decoder never
+// blocks, but the samples are dropped.
+func (ls *LiveSession) drain() {
+	defer close(ls.drained)
+	maxPending := int(MaxLiveBacklog.Seconds() * whisper.SampleRate)
+	for {
+		samples, err := ls.stream.ReadSamples()
+		if len(samples) > 0 {
+			ls.mu.Lock()
+			if len(ls.pending)+len(samples) > maxPending {
+				ls.behind = true
+			}
+			if !ls.behind {
+				ls.pending = append(ls.pending, samples...)
+			}
+			ls.mu.Unlock()
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("live audio stream failed: %v", err)
+			}
+			return
+		}
+	}
+}
+
+func (ls *LiveSession) run(ctx context.Context) {
+	defer ls.loop.Done()
+
+	step := time.NewTicker(LiveStep)
+	defer step.Stop()
+	summary := time.NewTicker(ls.opts.SummaryEvery)
+	defer summary.Stop()
+
+	started := time.Now()
+	for {
+		select {
+		case <-ls.stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-step.C:
+			if err := ls.step(false); err != nil {
+				log.Print(err)
+			}
+		case <-summary.C:
+			ls.rollingSummary(ctx, time.Since(started))
+		}
+	}
+}
+
+// step transcribes the uncommitted audio, commits what is final and sends
+// the rest as a partial. With flush set everything in the window is
+// committed.
+func (ls *LiveSession) step(flush bool) error {
+	maxSamples := int(LiveMaxWindow.Seconds() * whisper.SampleRate)
+
+	ls.mu.Lock()
+	audio := ls.pending[:min(len(ls.pending), maxSamples)]
+	audio = append([]float32(nil), audio...)
+	start := ls.pendingStart
+	ls.mu.Unlock()
+
+	// whisper needs some context to say anything useful
+	if len(audio) < whisper.SampleRate/2 && !flush {
+		return nil
+	}
+	segs, err := ls.s.transcribeSamples(audio, start)
+	if err != nil {
+		return fmt.Errorf("live transcription failed: %w", err)
+	}
+
+	commit := 0
+	switch {
+	case flush || len(audio) >= maxSamples:
+		commit = len(segs)
+	case len(segs) > 1:
+		commit = len(segs) - 1
+	}
+
+	if commit > 0 || len(audio) >= maxSamples || flush {
+		// committed audio is dropped up to the end of the last final
+		// segment, or entirely when everything was committed
+		cut := len(audio)
+		if commit < len(segs) {
+			cut = min(cut, int((segs[commit-1].End-start.Seconds())*whisper.SampleRate))
+		}
+		ls.mu.Lock()
+		ls.pending = ls.pending[cut:]
+		ls.pendingStart += time.Duration(cut) * time.Second / whisper.SampleRate
+		ls.final = append(ls.final, segs[:commit]...)
+		ls.mu.Unlock()
+
+		if commit > 0 {
+			ls.send(LiveEvent{Type: LiveFinal, Segments: segs[:commit]})
+		}
+	}
+
+	if rest := segs[commit:]; len(rest) > 0 {
+		ls.send(LiveEvent{Type: LivePartial, Segment: &db.TranscriptSegment{
+			Start: rest[0].Start,
+			End:   rest[len(rest)-1].End,
+			Text:  segmentsText(rest),
+		}})
+	}
+	return nil
+}
+
+func (ls *LiveSession) pendingSamples() int {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return len(ls.pending)
+}
+
+func (ls *LiveSession) transcript() []db.TranscriptSegment {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return append([]db.TranscriptSegment(nil), ls.final...)
+}
+
+// rollingSummary summarizes everything committed so far.
+func (ls *LiveSession) rollingSummary(ctx context.Context, elapsed time.Duration) {
+	segs := ls.transcript()
+	if len(segs) == 0 {
+		return
+	}
+	source := fmt.Sprintf("the first %d minutes of a meeting that is still going on", int(elapsed.Minutes()))
+	summary, err := ls.s.AiGenResponse(ctx, segmentsText(segs), source)
+	if err != nil {
+		log.Printf("live summary failed: %v", err)
+		return
+	}
+	ls.send(LiveEvent{Type: LiveSummary, Summary: summary})
+}
+
+// Close ends the stream, commits the remaining audio and stores the whole
+// session as a content item with its timed transcript.
+func (ls *LiveSession) Close(ctx context.Context) (*db.SummaryContent, error) {
+	ls.stream.Close()
+	<-ls.drained
+	if err := ls.stream.Wait(); err != nil {
+		log.Printf("live audio decoder exited: %v", err)
+	}
+	close(ls.stop)
+	ls.loop.Wait()
+
+	// a flush only covers one window, and whatever whisper had not caught up
+	// with yet is still pending
+	for ls.pendingSamples() > 0 {
+		if err := ls.step(true); err != nil {
+			return nil, err
+		}
+	}
+	segs := ls.transcript()
+	if len(segs) == 0 {
+		return nil, ErrEmptySession
+	}
+
+	source := "a recorded live meeting"
+	if ls.opts.Title != "" {
+		source = fmt.Sprintf("%s titled %q", source, ls.opts.Title)
+	}
+	content := segmentsText(segs)
+	summary, err := ls.s.AiGenResponse(ctx, content, source)
+	if err != nil {
+		return nil, err
+	}
+
+	return ls.s.Db.CreateMediaContent(ctx, db.MediaContent{
+		Content:     content,
+		AiSummary:   summary,
+		Segments:    segs,
+		Title:       ls.opts.Title,
+		Transcriber: string(TranscriberWhisper),
+	})
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/lupppig/briefly/db/mini"
+	"github.com/lupppig/briefly/media"
+	"github.com/minio/minio-go/v7"
+)
+
+// transcodeToWav converts anything ffmpeg can read into the 16 kHz mono WAV whisper expects.
+func transcodeToWav(ctx context.Context, inPath, outPath string) error {
+	cmd := exec.CommandContext(
+		ctx,
+		"ffmpeg",
+		"-y",
+		"-i", inPath,
+		"-vn",
+		"-acodec", "pcm_s16le",
+		"-ar", "16000",
+		"-ac", "1",
+		outPath,
+	)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg extract audio failed: %v\noutput: %s", err, out)
+	}
+	return nil
+}
+
+func (s *Service) putLocalFile(ctx context.Context, bucket, objectPath, localPath, contentType string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", localPath, err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", localPath, err)
+	}
+
+	_, err = s.Mc.MinClient.PutObject(
+		ctx,
+		bucket,
+		objectPath,
+		file,
+		fileInfo.Size(),
+		minio.PutObjectOptions{
+			ContentType: contentType,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upload to minio: %w", err)
+	}
+	return nil
+}
+
+// DeriveUploadAudio extracts the audio track of an uploaded audio or video file
+// into uploads/audio/<hash>.wav and returns its object key along with the
+// probed details of the original media. The derived WAV is only produced once per hash.
+func (s *Service) DeriveUploadAudio(ctx context.Context, fi io.Reader, hash, ext string) (string, *media.Info, error) {
+	srcFile, err := os.CreateTemp("", "upload-*"+ext)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(srcFile.Name())
+	defer srcFile.Close()
+
+	if _, err := io.Copy(srcFile, fi); err != nil {
+		return "", nil, fmt.Errorf("failed to write upload to temp file: %w", err)
+	}
+
+	info, err := media.ProbeFile(ctx, srcFile.Name())
+	if err != nil {
+		return "", nil, err
+	}
+
+	audioKey := filepath.Join("uploads", "audio", hash+".wav")
+	exists, err := s.Mc.ObjectExists(mini.DocumentBucket, audioKey)
+	if err != nil {
+		return "", nil, err
+	}
+	if exists {
+		return audioKey, info, nil
+	}
+
+	wavPath := srcFile.Name() + ".16k.wav"
+	defer os.Remove(wavPath)
+
+	if err := transcodeToWav(ctx, srcFile.Name(), wavPath); err != nil {
+		return "", nil, err
+	}
+
+	if err := s.putLocalFile(ctx, mini.DocumentBucket, audioKey, wavPath, "audio/wav"); err != nil {
+		return "", nil, err
+	}
+
+	return audioKey, info, nil
+}
+
+// LoudnessStats is stored with summaries of preprocessed audio, so the
+// effect of the filters can be checked.
+type LoudnessStats struct {
+	Before *media.Loudness `json:"before"`
+	After  *media.Loudness `json:"after"`
+}
+
+// preprocessAudio runs the stored WAV at audioKey through p and stores the
+// result next to it, returning its key and the loudness before and after.
+func (s *Service) preprocessAudio(ctx context.Context, audioKey string, p media.Preprocess) (string, *LoudnessStats, error) {
+	buf, err := s.Mc.GetObjectBuffer(mini.DocumentBucket, audioKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read audio from MinIO: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "preprocess-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	raw := filepath.Join(dir, "raw.wav")
+	if err := os.WriteFile(raw, buf.Bytes(), 0o600); err != nil {
+		return "", nil, err
+	}
+	processed := filepath.Join(dir, "processed.wav")
+	if err := media.PreprocessAudio(ctx, raw, processed, p); err != nil {
+		return "", nil, err
+	}
+
+	var stats LoudnessStats
+	if stats.Before, err = media.MeasureLoudness(ctx, raw); err != nil {
+		return "", nil, err
+	}
+	if stats.After, err = media.MeasureLoudness(ctx, processed); err != nil {
+		return "", nil, err
+	}
+
+	// "+" reads as a space in some S3 clients, so it stays out of keys
+	key := strings.TrimSuffix(audioKey, ".wav") + "_" + strings.ReplaceAll(p.Key(), "+", "_") + ".wav"
+	if err := s.putLocalFile(ctx, mini.DocumentBucket, key, processed, "audio/wav"); err != nil {
+		return "", nil, err
+	}
+	return key, &stats, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lupppig/briefly/media"
+	"github.com/lupppig/briefly/utils"
+)
+
+var (
+	// MaxCollectionVideos caps how many videos of a playlist or channel are
+	// processed; channels are listed newest first.
+	MaxCollectionVideos = 50
+	// ListingTimeout bounds the yt-dlp flat listing of a playlist or channel.
+	ListingTimeout = 2 * time.Minute
+)
+
+type ytListing struct {
+	Title   string    `json:"title"`
+	Entries []ytEntry `json:"entries"`
+}
+
+type ytEntry struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	LiveStatus string `json:"live_status"`
+}
+
+// fetchable reports whether the entry has a recording to download now.
+// Private and deleted videos stay in playlists, and upcoming or running
+// streams have nothing to fetch yet.
+func (e ytEntry) fetchable() bool {
+	if e.Title == "[Private video]" || e.Title == "[Deleted video]" {
+		return false
+	}
+	if e.LiveStatus == "is_upcoming" || e.LiveStatus == "is_live" {
+		return false
+	}
+	_, err := utils.ValidateYouTubeURL("https://www.youtube.com/watch?v=" + e.ID)
+	return err == nil
+}
+
+// listYouTubeCollection asks yt-dlp for the first limit videos of a playlist
+// or channel without resolving each of them, which keeps large listings fast.
+func listYouTubeCollection(ctx context.Context, coll *utils.YouTubeCollection, limit int) (*ytListing, error) {
+	ctx, cancel := context.WithTimeout(ctx, ListingTimeout)
+	defer cancel()
+
+	out, err := media.RunYTDLP(ctx, coll.URL,
+		"--flat-playlist",
+		"--dump-single-json",
+		"--playlist-end", strconv.Itoa(limit),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp listing failed: %w", err)
+	}
+
+	var listing ytListing
+	if err := json.Unmarshal(out, &listing); err != nil {
+		return nil, fmt.Errorf("failed to parse yt-dlp listing: %w", err)
+	}
+	return &listing, nil
+}
+
+// ProcessYoutubeCollectionJob expands a playlist or channel into one child
+// job per video and, once they have all finished, summarizes the whole
+// collection from the per-video summaries. Videos that fail are reported but
+// do not fail the collection. opts applies to every video.
+func (s *Service) ProcessYoutubeCollectionJob(jobID, link string, opts MediaOptions) {
+	update := func(status string, summary interface{}, errMsg string) {
+		s.JobManager.UpdateJob(jobID, status, summary, errMsg)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			update("error", "", fmt.Sprintf("panic: %v", r))
+		}
+	}()
+
+	update("validating_url", "", "")
+	yc, err := utils.ParseYouTubeCollection(link)
+	if err != nil {
+		update("error", "", err.Error())
+		return
+	}
+
+	ctx := context.Background()
+	update("listing_videos", "", "")
+	listing, err := listYouTubeCollection(ctx, yc, MaxCollectionVideos)
+	if err != nil {
+		update("error", "", "failed to list videos")
+		return
+	}
+
+	var tasks []childTask
+	for _, e := range listing.Entries {
+		if !e.fetchable() {
+			continue
+		}
+		videoURL := "https://www.youtube.com/watch?v=" + e.ID
+		tasks = append(tasks, childTask{
+			Title: e.Title,
+			Link:  videoURL,
+			run:   func(id string) { s.ProcessYoutubeJob(id, videoURL, opts) },
+		})
+	}
+	if len(tasks) == 0 {
+		update("error", "", fmt.Sprintf("the %s has no videos", yc.Kind))
+		return
+	}
+
+	coll, err := s.Db.CreateCollection(ctx, yc.Kind, yc.URL, listing.Title)
+	if err != nil {
+		update("error", "", "failed db fetch")
+		return
+	}
+	result := &CollectionResult{Collection: coll}
+
+	update("processing_videos", "", "")
+	result.Items = s.runChildJobs(jobID, tasks)
+
+	update("summarizing", "", "")
+	name := strings.TrimSpace(listing.Title)
+	if name == "" {
+		name = yc.ID
+	}
+	source := fmt.Sprintf("the per-video summaries of the YouTube %s %q", yc.Kind, name)
+	result.Digest, err = s.summarizeCollection(ctx, coll, result.Items, source)
+	if err != nil {
+		update("error", result, err.Error())
+		return
+	}
+
+	update("done", result, "")
+}
@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lupppig/briefly/db/mini"
+	db "github.com/lupppig/briefly/db/postgres"
+	"github.com/lupppig/briefly/utils"
+)
+
+var (
+	// MaxResumableUpload caps the declared length of a resumable upload.
+	MaxResumableUpload int64 = 4 << 30
+	// MaxResumableDocument caps documents and images, which are read into
+	// memory to extract their text, at the limit of a regular upload.
+	MaxResumableDocument int64 = 20 << 20
+	// ResumableUploadTTL is how long an upload may go without receiving
+	// bytes before it is aborted and its parts are dropped.
+	ResumableUploadTTL = 24 * time.Hour
+	// ResumablePartSize is the size of the multipart parts uploads are
+	// stored in. Object storage wants at least 5 MB for every part but the
+	// last.
+	ResumablePartSize int64 = 8 << 20
+	// ResumableSpoolDir holds the bytes of each upload that do not fill a
+	// part yet.
+	ResumableSpoolDir = filepath.Join(os.TempDir(), "briefly-uploads")
+)
+
+var (
+	ErrUploadNotFound = errors.New("upload not found")
+	ErrUploadOffset   = errors.New("upload offset does not match the bytes received")
+	ErrUploadLength   = fmt.Errorf("upload length must be between 1 byte and %d MB", MaxResumableUpload>>20)
+	ErrUploadDocument = fmt.Errorf("documents and images are limited to %d MB", MaxResumableDocument>>20)
+	ErrUploadOverrun  = errors.New("upload is longer than its declared length")
+	ErrUploadBusy     = errors.New("upload is being written by another request")
+	ErrUploadDone     = errors.New("upload is already complete")
+	ErrUploadChecksum = errors.New("sha256 of the upload does not match its checksum")
+)
+
+// uploadLocks keeps two requests from appending to the same upload at once.
+var uploadLocks sync.Map
+
+// CreateResumableUpload registers a tus upload of length bytes and starts the
+// multipart upload its parts go to. checksum is the hex SHA-256 the client
+// expects, or "" to skip the check; metadata is kept for processing once the
+// upload is complete.
+func (s *Service) CreateResumableUpload(ctx context.Context, filename string, length int64, checksum string, metadata map[string]string) (*db.ResumableUpload, error) {
+	if length < 1 || length > MaxResumableUpload {
+		return nil, ErrUploadLength
+	}
+	contentType := utils.ContentTypeByExtension(filename)
+	if contentType == "" {
+		return nil, fmt.Errorf("unsupported file extension: %s", filepath.Ext(filename))
+	}
+	if length > MaxResumableDocument && !isMediaType(contentType) {
+		return nil, ErrUploadDocument
+	}
+
+	u := db.ResumableUpload{
+		ID:          utils.NewJobID(),
+		Filename:    filepath.Base(filename),
+		ContentType: contentType,
+		Length:      length,
+		Metadata:    metadata,
+	}
+	if checksum != "" {
+		checksum = strings.ToLower(checksum)
+		u.Checksum = &checksum
+	}
+	u.ObjectKey = filepath.Join("uploads", "tus", u.ID)
+
+	var err error
+	u.MultipartID, err = s.Mc.NewMultipartUpload(ctx, mini.DocumentBucket, u.ObjectKey, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return s.Db.CreateResumableUpload(ctx, u)
+}
+
+// ResumableUpload returns an upload with its offset, which includes the
+// bytes received but not yet stored as a part. Those bytes only live on local
+// disk, so after a restart the offset falls back to the last stored part and
+// the client resends the rest.
+func (s *Service) ResumableUpload(ctx context.Context, id string) (*db.ResumableUpload, int64, error) {
+	u, err := s.Db.GetResumableUpload(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if u == nil {
+		return nil, 0, ErrUploadNotFound
+	}
+	return u, u.Offset + spoolSize(id, u.Offset), nil
+}
+
+// spoolPath names the spool after the offset it starts at, so bytes that
+// were stored as parts but not yet dropped from an old spool are never
+// counted twice.
+func spoolPath(id string, offset int64) string {
+	return filepath.Join(ResumableSpoolDir, fmt.Sprintf("%s.%d", id, offset))
+}
+
+func spoolSize(id string, offset int64) int64 {
+	fi, err := os.Stat(spoolPath(id, offset))
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// WriteResumableChunk appends body to an upload at offset, as a tus PATCH
+// does. Every full part is sent to object storage; the remainder waits in
+// the spool for the next chunk. Bytes read before body fails are kept, and
+// the returned offset counts them. It reports whether the upload is now
+// complete.
+func (s *Service) WriteResumableChunk(ctx context.Context, id string, offset int64, body io.Reader) (int64, bool, error) {
+	mu, _ := uploadLocks.LoadOrStore(id, &sync.Mutex{})
+	if !mu.(*sync.Mutex).TryLock() {
+		return 0, false, ErrUploadBusy
+	}
+	defer mu.(*sync.Mutex).Unlock()
+
+	u, current, err := s.ResumableUpload(ctx, id)
+	if err != nil {
+		return 0, false, err
+	}
+	if u.Status != db.UploadReceiving {
+		return current, false, ErrUploadDone
+	}
+	if offset != current {
+		return current, false, ErrUploadOffset
+	}
+
+	if err := os.MkdirAll(ResumableSpoolDir, 0o700); err != nil {
+		return current, false, err
+	}
+	spool, err := os.OpenFile(spoolPath(id, u.Offset), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return current, false, err
+	}
+	_, copyErr := io.Copy(spool, io.LimitReader(body, u.Length-current))
+	spool.Close()
+	if copyErr == nil {
+		// anything past the declared length is an error, but what fits is kept
+		var b [1]byte
+		if m, _ := body.Read(b[:]); m > 0 {
+			copyErr = ErrUploadOverrun
+		}
+	}
+
+	committed, err := s.flushSpool(ctx, u)
+	if err != nil {
+		log.Printf("could not store upload part: %v", err)
+		return committed + spoolSize(id, committed), false, err
+	}
+	current = committed + spoolSize(id, committed)
+	if copyErr != nil {
+		return current, false, copyErr
+	}
+	if current < u.Length {
+		return current, false, nil
+	}
+	if err := s.completeResumableUpload(ctx, u); err != nil {
+		return current, false, err
+	}
+	return current, true, nil
+}
+
+// completeResumableUpload assembles the parts of an upload that has all its
+// bytes and marks it complete. If an earlier attempt assembled the object but
+// could not record it, the object is found in place and the upload is only
+// marked, so an empty PATCH at the final offset finishes it.
+func (s *Service) completeResumableUpload(ctx context.Context, u *db.ResumableUpload) error {
+	exists, err := s.Mc.ObjectExists(mini.DocumentBucket, u.ObjectKey)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := s.Mc.CompleteMultipartUpload(ctx, mini.DocumentBucket, u.ObjectKey, u.MultipartID, u.PartETags); err != nil {
+			return err
+		}
+	}
+	os.Remove(spoolPath(u.ID, u.Offset))
+	if err := s.Db.UpdateResumableUploadStatus(ctx, u.ID, db.UploadComplete, "", ""); err != nil {
+		return err
+	}
+	uploadLocks.Delete(u.ID)
+	return nil
+}
+
+// isMediaType reports whether uploads of contentType are audio or video,
+// which are streamed from disk rather than read into memory.
+func isMediaType(contentType string) bool {
+	return strings.HasPrefix(contentType, "audio/") || strings.HasPrefix(contentType, "video/")
+}
+
+// flushSpool sends every full part in the spool to object storage, and the
+// partial last one once the upload has all its bytes. The bytes left over
+// move to the spool of the new offset. It returns the offset stored in parts;
+// u.Offset and u.PartETags are kept up to date.
+func (s *Service) flushSpool(ctx context.Context, u *db.ResumableUpload) (int64, error) {
+	f, err := os.Open(spoolPath(u.ID, u.Offset))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return u.Offset, nil
+		}
+		return u.Offset, err
+	}
+	defer f.Close()
+
+	size := spoolSize(u.ID, u.Offset)
+	final := u.Offset+size == u.Length
+	var sent int64
+	for size-sent >= ResumablePartSize || (final && sent < size) {
+		n := min(ResumablePartSize, size-sent)
+		var etag string
+		etag, err = s.Mc.PutPart(ctx, mini.DocumentBucket, u.ObjectKey, u.MultipartID,
+			len(u.PartETags)+1, io.NewSectionReader(f, sent, n), n)
+		if err != nil {
+			break
+		}
+		if err = s.Db.AddResumableUploadPart(ctx, u.ID, etag, n); err != nil {
+			break
+		}
+		u.PartETags = append(u.PartETags, etag)
+		u.Offset += n
+		sent += n
+	}
+	// parts stored before a failure leave the spool all the same
+	if terr := moveSpool(f, sent, spoolPath(u.ID, u.Offset)); err == nil {
+		err = terr
+	}
+	return u.Offset, err
+}
+
+// moveSpool copies what follows the first sent bytes of the spool f, which
+// are stored as parts by now, to the spool at path and removes f.
+func moveSpool(f *os.File, sent int64, path string) error {
+	if sent == 0 {
+		return nil
+	}
+	tmp, err := os.CreateTemp(ResumableSpoolDir, "spool-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, io.NewSectionReader(f, sent, 1<<62)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return os.Remove(f.Name())
+}
+
+// DeleteResumableUpload terminates an upload that has not completed and
+// drops what was received.
+func (s *Service) DeleteResumableUpload(ctx context.Context, id string) error {
+	u, err := s.Db.GetResumableUpload(ctx, id)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return ErrUploadNotFound
+	}
+	if u.Status != db.UploadReceiving {
+		return ErrUploadDone
+	}
+	if err := s.Mc.AbortMultipartUpload(ctx, mini.DocumentBucket, u.ObjectKey, u.MultipartID); err != nil {
+		log.Printf("could not abort multipart upload %s: %v", u.MultipartID, err)
+	}
+	os.Remove(spoolPath(id, u.Offset))
+	uploadLocks.Delete(id)
+	return s.Db.DeleteResumableUpload(ctx, id)
+}
+
+// StartUploadJanitor expires abandoned uploads once an hour until ctx is
+// done.
+func (s *Service) StartUploadJanitor(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			s.ExpireResumableUploads(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// ExpireResumableUploads aborts the uploads that have received nothing for
+// ResumableUploadTTL, dropping their parts and spool. The spool is checked as
+// well as the database, since bytes that do not fill a part yet only touch
+// the spool.
+func (s *Service) ExpireResumableUploads(ctx context.Context) {
+	before := time.Now().Add(-ResumableUploadTTL)
+	uploads, err := s.Db.StaleResumableUploads(ctx, before)
+	if err != nil {
+		log.Printf("failed to list stale uploads: %v", err)
+		return
+	}
+	for _, u := range uploads {
+		if fi, err := os.Stat(spoolPath(u.ID, u.Offset)); err == nil && fi.ModTime().After(before) {
+			continue
+		}
+		mu, _ := uploadLocks.LoadOrStore(u.ID, &sync.Mutex{})
+		if !mu.(*sync.Mutex).TryLock() {
+			continue
+		}
+		if err := s.Mc.AbortMultipartUpload(ctx, mini.DocumentBucket, u.ObjectKey, u.MultipartID); err != nil {
+			log.Printf("could not abort multipart upload %s: %v", u.MultipartID, err)
+		}
+		os.Remove(spoolPath(u.ID, u.Offset))
+		if err := s.Db.DeleteResumableUpload(ctx, u.ID); err != nil {
+			log.Printf("could not delete upload %s: %v", u.ID, err)
+		}
+		mu.(*sync.Mutex).Unlock()
+		uploadLocks.Delete(u.ID)
+	}
+}
+
+// ProcessResumableUploadJob checks a complete upload against its checksum,
+// moves it to where a regular upload of the same file would be stored and
+// runs it through AudioDocService.
+func (s *Service) ProcessResumableUploadJob(jobID, uploadID string, opts UploadOptions) {
+	ctx := context.Background()
+	update := func(status string, summary interface{}, errMsg string) {
+		s.JobManager.UpdateJob(jobID, status, summary, errMsg)
+		switch status {
+		case "done", "cached_summary_found":
+			s.Db.UpdateResumableUploadStatus(ctx, uploadID, db.UploadComplete, jobID, "")
+		case "error":
+			s.Db.UpdateResumableUploadStatus(ctx, uploadID, db.UploadFailed, jobID, errMsg)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			update("error", "", fmt.Sprintf("panic: %v", r))
+		}
+	}()
+
+	u, err := s.Db.GetResumableUpload(ctx, uploadID)
+	if err != nil || u == nil {
+		update("error", "", ErrUploadNotFound.Error())
+		return
+	}
+	s.Db.UpdateResumableUploadStatus(ctx, uploadID, db.UploadComplete, jobID, "")
+
+	update("verifying", "", "")
+	dir, err := os.MkdirTemp("", "resumable-*")
+	if err != nil {
+		update("error", "", err.Error())
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "upload"+filepath.Ext(u.Filename))
+	if err := s.Mc.DownloadObject(ctx, mini.DocumentBucket, u.ObjectKey, path); err != nil {
+		update("error", "", err.Error())
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		update("error", "", err.Error())
+		return
+	}
+	defer f.Close()
+
+	hash, err := utils.HashFile(f)
+	if err != nil {
+		update("error", "", err.Error())
+		return
+	}
+	if u.Checksum != nil && *u.Checksum != hash {
+		s.Mc.RemoveObject(ctx, mini.DocumentBucket, u.ObjectKey)
+		update("error", "", ErrUploadChecksum.Error())
+		return
+	}
+
+	fh := &multipart.FileHeader{
+		Filename: u.Filename,
+		Size:     u.Length,
+		Header:   textproto.MIMEHeader{"Content-Type": {u.ContentType}},
+	}
+	isDoc := utils.IsDoc(f, fh)
+	// the extension passed the length check at creation, the content may not
+	if isDoc && u.Length > MaxResumableDocument {
+		s.Mc.RemoveObject(ctx, mini.DocumentBucket, u.ObjectKey)
+		update("error", "", ErrUploadDocument.Error())
+		return
+	}
+	key := uploadKey(hash, isDoc, !isDoc && utils.IsVideo(u.Filename))
+	// AudioDocService finds the object in place and does not upload it again
+	if exists, _ := s.Mc.ObjectExists(mini.DocumentBucket, key); !exists {
+		if err := s.Mc.CopyObject(ctx, mini.DocumentBucket, u.ObjectKey, key); err != nil {
+			update("error", "", err.Error())
+			return
+		}
+	}
+	s.Mc.RemoveObject(ctx, mini.DocumentBucket, u.ObjectKey)
+
+	update("processing", "", "")
+	summ, err := s.AudioDocService(f, fh, opts)
+	if err != nil {
+		update("error", "", err.Error())
+		return
+	}
+	update("done", summ, "")
+}
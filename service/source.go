@@ -0,0 +1,361 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lupppig/briefly/db/mini"
+	db "github.com/lupppig/briefly/db/postgres"
+	"github.com/lupppig/briefly/media"
+)
+
+// PreferCaptions makes media jobs use the platform's captions when there are
+// any and only fall back to downloading and transcribing the audio without.
+var PreferCaptions = false
+
+// sourceNames describe each provider's items to the summarizer.
+var sourceNames = map[string]string{
+	"youtube": "youtube video",
+	"vimeo":   "vimeo video",
+	"direct":  "audio recording",
+	"ytdlp":   "online video",
+}
+
+// MediaOptions carries per-request settings of a media job.
+type MediaOptions struct {
+	// Range limits the job to part of the recording.
+	Range media.TimeRange
+	// Title replaces the provider's title in the prompt, for sources such as
+	// podcast enclosures whose file name says nothing.
+	Title string
+	// Transcriber picks whisper (the default) or Gemini for the audio.
+	Transcriber Transcriber
+	// Preprocess filters the audio before it is transcribed. Summaries are
+	// cached per preprocessing, so the same source can be compared with and
+	// without it.
+	Preprocess media.Preprocess
+	// Keyframes adds a timeline of stills taken at scene changes, and
+	// CaptionKeyframes has each of them described by the multimodal model.
+	Keyframes        bool
+	CaptionKeyframes bool
+}
+
+// ProcessMediaJob summarizes a video or audio link from any supported
+// provider.
+func (s *Service) ProcessMediaJob(jobID, link string, opts MediaOptions) {
+	s.processSource(jobID, link, opts)
+}
+
+// processSource runs a media job against the given providers, or all of them.
+// Items are cached per (provider, external ID) and range, so the same video
+// linked in different ways is only processed once.
+func (s *Service) processSource(jobID, link string, opts MediaOptions, providers ...media.SourceProvider) {
+	update := func(status string, summary interface{}, errMsg string) {
+		s.JobManager.UpdateJob(jobID, status, summary, errMsg)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			update("error", "", fmt.Sprintf("panic: %v", r))
+		}
+	}()
+
+	update("validating_url", "", "")
+	provider, src, err := media.ResolveSource(link, providers...)
+	if err != nil {
+		update("error", "", err.Error())
+		return
+	}
+	// a link that points at a moment (t=) starts there unless the request
+	// gives its own start
+	if opts.Range.Start == 0 && src.Start > 0 && (opts.Range.End == 0 || src.Start < opts.Range.End) {
+		opts.Range.Start = src.Start
+	}
+	if err := opts.Range.Validate(); err != nil {
+		update("error", "", err.Error())
+		return
+	}
+	src.Range = opts.Range
+	rng := contentRange(opts.Range)
+
+	ctx := context.Background()
+	update("checking_cache", "", "")
+
+	ms, err := s.Db.GetOrCreateMediaSource(ctx, src.Provider, src.ExternalID, src.URL)
+	if err != nil {
+		update("error", "", "failed db fetch")
+		return
+	}
+
+	transcribers := []string{opts.Transcriber.cacheKey()}
+	if PreferCaptions && rng == nil {
+		transcribers = append(transcribers, string(TranscriberCaptions))
+	}
+	saved, _ := s.Db.GetContentBySourceID(ctx, ms.ID, rng, opts.Preprocess.Key(), transcribers)
+	if saved != nil {
+		saved.Segments, _ = s.Db.GetTranscriptSegments(ctx, saved.Id)
+		// summaries cached before metadata was stored get it on their next hit
+		s.sourceMetadata(ctx, provider, src, ms)
+		saved.Metadata = ms.Metadata
+		update("cached_summary_found", saved, s.withKeyframes(ctx, provider, src, ms, saved, opts, update))
+		return
+	}
+
+	update("fetching_metadata", "", "")
+	title := opts.Title
+	meta := s.sourceMetadata(ctx, provider, src, ms)
+	if meta != nil {
+		if meta.Duration > 0 && opts.Range.Start.Seconds() >= meta.Duration {
+			update("error", "", ErrRangeOutOfBounds.Error())
+			return
+		}
+		if title == "" {
+			title = meta.Title
+		}
+	}
+	source := sourceNames[src.Provider]
+	if title != "" {
+		source = fmt.Sprintf("%s titled %q", source, title)
+	}
+	if meta != nil && meta.Channel != "" && opts.Title == "" {
+		source = fmt.Sprintf("%s from the channel %q", source, meta.Channel)
+	}
+	if rng != nil {
+		source = fmt.Sprintf("part of a %s (%s)", source, opts.Range)
+	}
+
+	var (
+		content, summary string
+		segments         []db.TranscriptSegment
+		loudness         *LoudnessStats
+		transcriber      = TranscriberCaptions
+	)
+	notes := meta.PromptContext(opts.Range)
+	// captions are only flattened to text, so they cannot be cut to a range
+	if PreferCaptions && rng == nil {
+		update("fetching_captions", "", "")
+		// any failure here just means the audio gets transcribed instead
+		content, _ = provider.FetchCaptions(ctx, src)
+	}
+
+	if content == "" {
+		audioPath, cached, err := s.sourceAudio(ctx, provider, src, ms)
+		if err != nil {
+			update("error", "", "failed to extract audio")
+			return
+		}
+		if cached {
+			update("cached_audio_found", "", "")
+		}
+		if !opts.Preprocess.IsZero() {
+			update("preprocessing_audio", "", "")
+			audioPath, loudness, err = s.preprocessAudio(ctx, audioPath, opts.Preprocess)
+			if err != nil {
+				update("error", "", "failed to preprocess audio")
+				return
+			}
+		}
+
+		update("transcribing", "", "")
+		segments, summary, transcriber, err = s.transcribe(ctx, opts.Transcriber, audioPath, opts.Range.Start, source, notes)
+		if err != nil {
+			update("error", "", "transcription failed")
+			return
+		}
+		content = segmentsText(segments)
+	}
+
+	if summary == "" {
+		update("summarizing", "", "")
+		text, instructions := content, []string(nil)
+		if notes != "" {
+			text = notes + "Transcript:\n" + content
+			instructions = append(instructions, metadataInstruction)
+		}
+		var err error
+		summary, err = s.AiGenResponse(ctx, text, source, instructions...)
+		if err != nil {
+			update("error", "", "summarize failed")
+			return
+		}
+	}
+
+	update("saving", "", "")
+	sumCon, err := s.Db.CreateMediaContent(ctx, db.MediaContent{
+		Content:     content,
+		AiSummary:   summary,
+		SourceID:    &ms.ID,
+		Range:       rng,
+		Segments:    segments,
+		Preprocess:  opts.Preprocess.Key(),
+		Loudness:    loudnessJSON(loudness),
+		Transcriber: string(transcriber),
+	})
+	if err != nil {
+		update("error", "", "failed to save content")
+		return
+	}
+	sumCon.Metadata = ms.Metadata
+
+	update("done", sumCon, s.withKeyframes(ctx, provider, src, ms, sumCon, opts, update))
+}
+
+// withKeyframes attaches the keyframe timeline when opts asks for it. The
+// summary stands on its own, so a failure is only reported alongside it.
+func (s *Service) withKeyframes(ctx context.Context, p media.SourceProvider, src *media.Source, ms *db.MediaSource, content *db.SummaryContent, opts MediaOptions, update func(string, interface{}, string)) string {
+	if !opts.Keyframes {
+		return ""
+	}
+	update("extracting_keyframes", "", "")
+	if err := s.attachKeyframes(ctx, p, src, ms, content, opts.CaptionKeyframes); err != nil {
+		log.Printf("failed to extract keyframes of %s: %v", src.URL, err)
+		if errors.Is(err, media.ErrNoVideo) {
+			return "keyframe extraction failed: " + err.Error()
+		}
+		return "keyframe extraction failed"
+	}
+	return ""
+}
+
+// metadataInstruction tells the summarizer how to use the description and
+// chapters that are put in front of a transcript.
+const metadataInstruction = "The content starts with the creator's description and chapter list, followed by the transcript. Summarize the transcript: follow the chapters to organize the summary and use the description only for names and context, ignoring its links, sponsor messages and calls to subscribe."
+
+// sourceMetadata returns the provider's metadata for src, fetching and
+// storing it on ms the first time. Metadata is best effort, so failures
+// return nil.
+func (s *Service) sourceMetadata(ctx context.Context, p media.SourceProvider, src *media.Source, ms *db.MediaSource) *media.SourceMetadata {
+	if len(ms.Metadata) > 0 {
+		var meta media.SourceMetadata
+		if err := json.Unmarshal(ms.Metadata, &meta); err == nil {
+			return &meta
+		}
+	}
+
+	meta, err := p.Metadata(ctx, src)
+	if err != nil {
+		log.Printf("failed to fetch metadata of %s: %v", src.URL, err)
+		return nil
+	}
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return meta
+	}
+	if err := s.Db.UpdateMediaSourceMetadata(ctx, ms.ID, raw); err != nil {
+		log.Printf("failed to save metadata of %s: %v", src.URL, err)
+	}
+	ms.Metadata = raw
+	return meta
+}
+
+// sourceAudio returns the MinIO key of the WAV for src, reporting whether it
+// was already stored. The whole recording is kept at the source's audio_path;
+// ranges are kept next to it under the source ID and cut from the whole
+// recording when that is already stored, so it is not downloaded again.
+func (s *Service) sourceAudio(ctx context.Context, p media.SourceProvider, src *media.Source, ms *db.MediaSource) (string, bool, error) {
+	fullExists := false
+	if ms.AudioPath != "" {
+		fullExists, _ = s.Mc.ObjectExists(mini.DocumentBucket, ms.AudioPath)
+	}
+
+	if src.Range.IsZero() {
+		if fullExists {
+			return ms.AudioPath, true, nil
+		}
+		objectPath := fmt.Sprintf("%s_audio/%d.wav", src.Provider, time.Now().UnixNano())
+		if err := s.fetchSourceAudio(ctx, p, src, objectPath); err != nil {
+			return "", false, err
+		}
+		s.Db.UpdateMediaSourceAudioPath(ctx, ms.ID, objectPath)
+		return objectPath, false, nil
+	}
+
+	objectPath := fmt.Sprintf("%s_audio/%s/%s.wav", src.Provider, ms.ID, src.Range.Key())
+	if exists, _ := s.Mc.ObjectExists(mini.DocumentBucket, objectPath); exists {
+		return objectPath, true, nil
+	}
+	if fullExists {
+		return objectPath, false, s.trimStoredAudio(ctx, ms.AudioPath, objectPath, src.Range)
+	}
+	return objectPath, false, s.fetchSourceAudio(ctx, p, src, objectPath)
+}
+
+// fetchSourceAudio downloads the audio of src, converts it to the WAV whisper
+// expects and stores it at objectPath in MinIO.
+func (s *Service) fetchSourceAudio(ctx context.Context, p media.SourceProvider, src *media.Source, objectPath string) error {
+	dir, err := os.MkdirTemp("", "source-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	downloaded, err := p.FetchAudio(ctx, src, dir)
+	if err != nil {
+		return err
+	}
+
+	wavPath := downloaded + ".wav"
+	if err := transcodeToWav(ctx, downloaded, wavPath); err != nil {
+		return err
+	}
+
+	return s.putLocalFile(ctx, mini.DocumentBucket, objectPath, wavPath, "audio/wav")
+}
+
+// trimStoredAudio cuts r out of a WAV already in MinIO and stores the result
+// at dstKey.
+func (s *Service) trimStoredAudio(ctx context.Context, srcKey, dstKey string, r media.TimeRange) error {
+	buf, err := s.Mc.GetObjectBuffer(mini.DocumentBucket, srcKey)
+	if err != nil {
+		return fmt.Errorf("failed to read audio from MinIO: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "trim-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	full := filepath.Join(dir, "full.wav")
+	if err := os.WriteFile(full, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	trimmed := filepath.Join(dir, "trimmed.wav")
+	if err := media.TrimAudio(ctx, full, trimmed, r); err != nil {
+		return err
+	}
+
+	return s.putLocalFile(ctx, mini.DocumentBucket, dstKey, trimmed, "audio/wav")
+}
+
+// contentRange converts a range to how it is stored; the whole recording is
+// nil.
+func contentRange(r media.TimeRange) *db.ContentRange {
+	if r.IsZero() {
+		return nil
+	}
+	cr := &db.ContentRange{Start: r.Start.Seconds()}
+	if r.End != 0 {
+		end := r.End.Seconds()
+		cr.End = &end
+	}
+	return cr
+}
+
+// loudnessJSON encodes stats for storage; nil stays nil.
+func loudnessJSON(stats *LoudnessStats) json.RawMessage {
+	if stats == nil {
+		return nil
+	}
+	raw, err := json.Marshal(stats)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
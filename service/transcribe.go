@@ -4,26 +4,49 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"time"
 
 	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
 	"github.com/go-audio/wav"
 
 	"github.com/lupppig/briefly/db/mini"
+	db "github.com/lupppig/briefly/db/postgres"
 )
 
 func (s *Service) TranscribeAudio(audioPath string) (string, error) {
+	segs, err := s.TranscribeSegments(audioPath, 0)
+	if err != nil {
+		return "", err
+	}
+	return segmentsText(segs), nil
+}
+
+// TranscribeSegments transcribes a WAV from MinIO into timed segments. offset
+// is added to every timestamp, so a transcript of a trimmed range keeps the
+// times of the original recording.
+func (s *Service) TranscribeSegments(audioPath string, offset time.Duration) ([]db.TranscriptSegment, error) {
 	buf, err := s.Mc.GetObjectBuffer(mini.DocumentBucket, audioPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read audio from MinIO: %w", err)
+		return nil, fmt.Errorf("failed to read audio from MinIO: %w", err)
 	}
-	samples, err := wavToFloat32(buf.Bytes())
+	return s.TranscribeWAV(buf.Bytes(), offset)
+}
+
+// TranscribeWAV transcribes a 16 kHz mono WAV held in memory; see
+// TranscribeSegments for offset.
+func (s *Service) TranscribeWAV(data []byte, offset time.Duration) ([]db.TranscriptSegment, error) {
+	samples, err := wavToFloat32(data)
 	if err != nil {
-		return "", fmt.Errorf("failed to convert WAV to float32 samples: %w", err)
+		return nil, fmt.Errorf("failed to convert WAV to float32 samples: %w", err)
 	}
+	return s.transcribeSamples(samples, offset)
+}
 
+// transcribeSamples runs whisper over 16 kHz mono samples.
+func (s *Service) transcribeSamples(samples []float32, offset time.Duration) ([]db.TranscriptSegment, error) {
 	ctx, err := s.WhisperModel.NewContext()
 	if err != nil {
-		return "", fmt.Errorf("failed to create whisper context: %w", err)
+		return nil, fmt.Errorf("failed to create whisper context: %w", err)
 	}
 
 	ctx.SetThreads(4)
@@ -33,21 +56,38 @@ func (s *Service) TranscribeAudio(audioPath string) (string, error) {
 	ctx.SetTokenTimestamps(false)
 
 	if err := ctx.Process(samples, nil, nil, nil); err != nil {
-		return "", fmt.Errorf("whisper process failed: %w", err)
+		return nil, fmt.Errorf("whisper process failed: %w", err)
 	}
-	var transcript strings.Builder
-	transcript.Grow(len(samples) / 100)
 
+	var segs []db.TranscriptSegment
 	for {
 		seg, err := ctx.NextSegment()
 		if err != nil {
 			break
 		}
-		transcript.WriteString(seg.Text)
-		transcript.WriteString(" ")
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+		segs = append(segs, db.TranscriptSegment{
+			Start: (seg.Start + offset).Seconds(),
+			End:   (seg.End + offset).Seconds(),
+			Text:  text,
+		})
 	}
 
-	return strings.TrimSpace(transcript.String()), nil
+	return segs, nil
+}
+
+func segmentsText(segs []db.TranscriptSegment) string {
+	var transcript strings.Builder
+	for i, seg := range segs {
+		if i > 0 {
+			transcript.WriteString(" ")
+		}
+		transcript.WriteString(seg.Text)
+	}
+	return transcript.String()
 }
 
 func wavToFloat32(wavBytes []byte) ([]float32, error) {
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+
+	"github.com/lupppig/briefly/extract"
+	"github.com/lupppig/briefly/outbound"
+	"github.com/lupppig/briefly/utils"
+)
+
+var (
+	// URLFetchTimeout bounds the whole fetch of a remote URL, body included.
+	URLFetchTimeout = 30 * time.Second
+	// URLMaxBytes caps how much of a remote resource is downloaded; it matches
+	// the upload limit.
+	URLMaxBytes int64 = 20 << 20
+)
+
+var (
+	ErrURLTooLarge    = errors.New("remote resource is too large")
+	ErrURLUnsupported = errors.New("unsupported remote content type")
+)
+
+type fetchedURL struct {
+	Body        []byte
+	ContentType string
+	ETag        string
+	FinalURL    string
+	NotModified bool
+}
+
+// fetchURL downloads link, sending If-None-Match when an ETag from an earlier
+// fetch is known.
+func fetchURL(ctx context.Context, link, etag string) (*fetchedURL, error) {
+	ctx, cancel := context.WithTimeout(ctx, URLFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "briefly/1.0 (+https://github.com/lupppig/briefly)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/pdf,text/plain;q=0.9,*/*;q=0.8")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := outbound.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch url: %w", err)
+	}
+	defer resp.Body.Close()
+
+	res := &fetchedURL{
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        resp.Header.Get("ETag"),
+		FinalURL:    resp.Request.URL.String(),
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		res.NotModified = true
+		return res, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch url: %s", resp.Status)
+	}
+	if resp.ContentLength > URLMaxBytes {
+		return nil, ErrURLTooLarge
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, URLMaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read url body: %w", err)
+	}
+	if int64(len(body)) > URLMaxBytes {
+		return nil, ErrURLTooLarge
+	}
+	res.Body = body
+	return res, nil
+}
+
+// ProcessURLJob summarizes a remote document or web page. Pages are cached by
+// normalized URL; a refetch that comes back 304 or with an unchanged body
+// reuses the stored summary.
+func (s *Service) ProcessURLJob(jobID, link string) {
+	update := func(status string, summary interface{}, errMsg string) {
+		s.JobManager.UpdateJob(jobID, status, summary, errMsg)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			update("error", "", fmt.Sprintf("panic: %v", r))
+		}
+	}()
+
+	update("validating_url", "", "")
+	normalized, err := utils.NormalizeURL(link)
+	if err != nil {
+		update("error", "", err.Error())
+		return
+	}
+
+	ctx := context.Background()
+	update("checking_cache", "", "")
+
+	page, err := s.Db.GetOrCreateWebPage(ctx, normalized)
+	if err != nil {
+		update("error", "", "failed db fetch")
+		return
+	}
+
+	saved, _ := s.Db.GetContentByWebPageID(ctx, page.ID)
+	etag := ""
+	if saved != nil && page.ETag != nil {
+		etag = *page.ETag
+	}
+
+	update("fetching", "", "")
+	res, err := fetchURL(ctx, normalized, etag)
+	if err != nil {
+		update("error", "", err.Error())
+		return
+	}
+	if res.NotModified && saved != nil {
+		update("cached_summary_found", saved, "")
+		return
+	}
+
+	sum := sha256.Sum256(res.Body)
+	hash := hex.EncodeToString(sum[:])
+	if saved != nil && page.ContentHash != nil && *page.ContentHash == hash {
+		s.Db.UpdateWebPageFetch(ctx, page.ID, res.FinalURL, res.ETag, hash, derefOr(page.MimeType, ""), derefOr(page.Title, ""))
+		update("cached_summary_found", saved, "")
+		return
+	}
+
+	update("extracting", "", "")
+	mimeType, doc, err := extractFetched(ctx, res)
+	if err != nil {
+		update("error", "", err.Error())
+		return
+	}
+	content := doc.Text()
+	if strings.TrimSpace(content) == "" {
+		update("error", "", ErrNoText.Error())
+		return
+	}
+
+	source := docSources[mimeType]
+	if mimeType == extract.MimeHTML {
+		source = "web article"
+	}
+	if doc.Title != "" {
+		source = fmt.Sprintf("%s titled %q", source, doc.Title)
+	}
+	instructions := docInstructions[mimeType]
+	if len(doc.Tables()) > 0 {
+		instructions = append(instructions, tableInstruction)
+	}
+
+	update("summarizing", "", "")
+	summary, err := s.AiGenResponse(ctx, content, source, instructions...)
+	if err != nil {
+		update("error", "", "summarize failed")
+		return
+	}
+
+	update("saving", "", "")
+	if _, err := s.Db.UpdateWebPageFetch(ctx, page.ID, res.FinalURL, res.ETag, hash, mimeType, doc.Title); err != nil {
+		update("error", "", "failed to save page")
+		return
+	}
+	sumCon, err := s.Db.ReplaceWebPageContent(ctx, page.ID, content, summary)
+	if err != nil {
+		update("error", "", "failed to save content")
+		return
+	}
+
+	update("done", sumCon, "")
+}
+
+// extractFetched sniffs the body and runs it through the document
+// extractors. HTML goes through the main-content extractor instead of the
+// whole-page one used for uploaded .html files.
+func extractFetched(ctx context.Context, res *fetchedURL) (string, *extract.Document, error) {
+	filename := ""
+	if u, err := url.Parse(res.FinalURL); err == nil {
+		filename = path.Base(u.Path)
+	}
+	mimeType := extract.DetectMIME(bytes.NewReader(res.Body), int64(len(res.Body)), filename)
+
+	// a text/plain sniff of a page served as HTML is still HTML
+	if declared, _, err := mime.ParseMediaType(res.ContentType); err == nil && declared == extract.MimeHTML && mimeType == extract.MimeText {
+		mimeType = extract.MimeHTML
+	}
+
+	if mimeType == extract.MimeHTML {
+		r, err := charset.NewReader(bytes.NewReader(res.Body), res.ContentType)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to decode page: %w", err)
+		}
+		body, err := io.ReadAll(r)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to decode page: %w", err)
+		}
+		doc, err := extract.ExtractArticle(body)
+		return mimeType, doc, err
+	}
+
+	if !extract.Supported(mimeType) {
+		return "", nil, fmt.Errorf("%w: %s", ErrURLUnsupported, mimeType)
+	}
+	doc, err := extract.Extract(ctx, mimeType, res.Body)
+	return mimeType, doc, err
+}
+
+func derefOr(s *string, def string) string {
+	if s == nil {
+		return def
+	}
+	return *s
+}
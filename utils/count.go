@@ -1,34 +1,39 @@
 package utils
 
 import (
+	"io"
 	"mime/multipart"
 
-	"github.com/faiface/beep/mp3"
-	"github.com/unidoc/unipdf/v3/model"
+	"github.com/lupppig/briefly/extract"
 )
 
-func GettMP3Duration(fi multipart.File) (float64, error) {
-	streamer, format, err := mp3.Decode(fi)
+// GetPDFPageCount decrypts the file with password when it is encrypted; an
+// empty password is fine for unencrypted files.
+func GetPDFPageCount(fi multipart.File, password string) (int, error) {
+
+	pdfReader, err := extract.OpenPDF(fi, password)
 	if err != nil {
 		return 0, err
 	}
-	defer streamer.Close()
 
-	duration := float64(streamer.Len()) / float64(format.SampleRate)
-	return duration, nil
-}
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return 0, err
+	}
 
-func GetPDFPageCount(fi multipart.File) (int, error) {
+	return numPages, nil
+}
 
-	pdfReader, err := model.NewPdfReader(fi)
+func GetSlideCount(fi multipart.File, mime string) (int, error) {
+	data, err := io.ReadAll(fi)
 	if err != nil {
 		return 0, err
 	}
 
-	numPages, err := pdfReader.GetNumPages()
+	deck, err := extract.ParseSlides(mime, data)
 	if err != nil {
 		return 0, err
 	}
 
-	return numPages, nil
+	return len(deck.Slides), nil
 }
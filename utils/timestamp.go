@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	unitTimestampRegex  = regexp.MustCompile(`^(?:(\d+)h)?(?:(\d+)m)?(?:(\d+(?:\.\d+)?)s?)?$`)
+	clockTimestampRegex = regexp.MustCompile(`^(?:(\d+):)?(\d{1,2}):(\d{1,2}(?:\.\d+)?)$`)
+)
+
+// maxTimestamp is far beyond any real recording; it keeps arithmetic on
+// parsed values from overflowing.
+const maxTimestamp = 1000 * time.Hour
+
+// ParseTimestamp reads a position in a recording. It accepts seconds ("90",
+// "90.5", "90s"), h/m/s forms ("1h2m3s", "4m") and clock forms ("1:30",
+// "01:02:03.5").
+func ParseTimestamp(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, errors.New("empty timestamp")
+	}
+
+	var h, m, sec string
+	if c := clockTimestampRegex.FindStringSubmatch(s); c != nil {
+		h, m, sec = c[1], c[2], c[3]
+		if mm, _ := strconv.Atoi(m); mm >= 60 && h != "" {
+			return 0, errors.New("invalid timestamp " + strconv.Quote(s))
+		}
+	} else if u := unitTimestampRegex.FindStringSubmatch(s); u != nil {
+		h, m, sec = u[1], u[2], u[3]
+	} else {
+		return 0, errors.New("invalid timestamp " + strconv.Quote(s))
+	}
+
+	var d time.Duration
+	for _, part := range []struct {
+		val  string
+		unit time.Duration
+	}{{h, time.Hour}, {m, time.Minute}} {
+		if part.val == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part.val)
+		if err != nil || n > int(maxTimestamp/part.unit) {
+			return 0, errors.New("invalid timestamp " + strconv.Quote(s))
+		}
+		d += time.Duration(n) * part.unit
+	}
+	if sec != "" {
+		f, err := strconv.ParseFloat(sec, 64)
+		if err != nil || f > maxTimestamp.Seconds() {
+			return 0, errors.New("invalid timestamp " + strconv.Quote(s))
+		}
+		d += time.Duration(f * float64(time.Second))
+	}
+	if d > maxTimestamp {
+		return 0, errors.New("invalid timestamp " + strconv.Quote(s))
+	}
+	return d, nil
+}
@@ -3,86 +3,374 @@ package utils
 import (
 	"errors"
 	"fmt"
-	"io"
 	"mime/multipart"
-	"net/http"
+	"net"
 	"net/url"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
+
+	"github.com/lupppig/briefly/extract"
 )
 
 var youtubeIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{11}$`)
-var playlistIDRegex = regexp.MustCompile(`^PL[a-zA-Z0-9_-]+$`)
 
-func ValidateYouTubeURL(link string) (string, error) {
+// youtubeHosts is the strict allowlist of hosts a video link may use. Hosts
+// that merely contain "youtube.com" (youtube.com.evil.net) are rejected.
+var youtubeHosts = map[string]bool{
+	"youtube.com":              true,
+	"www.youtube.com":          true,
+	"m.youtube.com":            true,
+	"music.youtube.com":        true,
+	"youtube-nocookie.com":     true,
+	"www.youtube-nocookie.com": true,
+	"youtu.be":                 true,
+}
+
+// videoPathPrefixes are the path forms that carry the video ID as their
+// second segment.
+var videoPathPrefixes = map[string]bool{
+	"shorts": true, "embed": true, "live": true, "v": true,
+}
+
+// YouTubeVideo is a parsed video link. URL is the canonical watch URL, which
+// is what gets stored and handed to yt-dlp; Start is the start time the link
+// pointed at (t=, start= or #t=), zero if it had none.
+type YouTubeVideo struct {
+	ID    string
+	URL   string
+	Start time.Duration
+}
+
+// ParseYouTubeURL accepts watch links on youtube.com, m.youtube.com and
+// music.youtube.com, /shorts/, /embed/, /live/ and /v/ paths,
+// youtube-nocookie.com embeds and youtu.be short links. Tracking parameters
+// such as si= are ignored.
+func ParseYouTubeURL(link string) (*YouTubeVideo, error) {
+	link = strings.TrimSpace(link)
 	if link == "" {
-		return "", errors.New("youtube link is required")
+		return nil, errors.New("youtube link is required")
+	}
+	// links are often pasted without a scheme
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
 	}
 
 	u, err := url.Parse(link)
 	if err != nil {
-		return "", errors.New("invalid url")
+		return nil, errors.New("invalid url")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("not a youtube link")
+	}
+	if u.User != nil || u.Port() != "" {
+		return nil, errors.New("not a youtube link")
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if !youtubeHosts[host] {
+		return nil, errors.New("not a youtube link")
 	}
 
 	q := u.Query()
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
 
-	if list := q.Get("list"); list != "" {
-		if playlistIDRegex.MatchString(list) || strings.HasPrefix(strings.ToUpper(list), "PL") {
-			return "", errors.New("playlist links are not supported — only single video links allowed")
+	var id string
+	switch {
+	case host == "youtu.be":
+		if len(parts) == 1 {
+			id = parts[0]
+		}
+	case strings.HasSuffix(host, "youtube-nocookie.com"):
+		if len(parts) == 2 && parts[0] == "embed" {
+			id = parts[1]
 		}
+	case len(parts) == 1 && parts[0] == "watch":
+		id = q.Get("v")
+	case len(parts) == 2 && videoPathPrefixes[parts[0]]:
+		id = parts[1]
+	case len(parts) >= 1 && parts[0] == "playlist":
+		return nil, errors.New("playlist links are not single videos")
+	default:
+		return nil, errors.New("not a youtube video link")
 	}
 
-	if strings.Contains(u.Path, "/playlist") {
-		return "", errors.New("playlist links are not supported — only single video links allowed")
+	if !youtubeIDRegex.MatchString(id) {
+		return nil, errors.New("invalid youtube video id")
 	}
 
-	host := strings.ToLower(u.Host)
+	return &YouTubeVideo{
+		ID:    id,
+		URL:   "https://www.youtube.com/watch?v=" + id,
+		Start: youtubeStart(u),
+	}, nil
+}
 
-	if host == "youtu.be" {
-		id := strings.TrimPrefix(u.Path, "/")
-		if youtubeIDRegex.MatchString(id) {
-			return id, nil
-		}
-		return "", errors.New("invalid youtube video id")
+// ValidateYouTubeURL returns the video ID of a YouTube video link.
+func ValidateYouTubeURL(link string) (string, error) {
+	v, err := ParseYouTubeURL(link)
+	if err != nil {
+		return "", err
+	}
+	return v.ID, nil
+}
+
+// youtubeStart reads the start time of a link (t=, start= or #t=). Values
+// that do not parse are ignored rather than failing the link.
+func youtubeStart(u *url.URL) time.Duration {
+	q := u.Query()
+	raw := q.Get("t")
+	if raw == "" {
+		raw = q.Get("start")
+	}
+	if raw == "" {
+		raw, _ = strings.CutPrefix(u.Fragment, "t=")
+	}
+	d, err := ParseTimestamp(raw)
+	if err != nil {
+		return 0
 	}
+	return d
+}
 
-	if strings.Contains(host, "youtube.com") {
+// YouTubeCollection is a playlist or channel link. URL is the canonical
+// listing URL that is handed to yt-dlp in place of the submitted link.
+type YouTubeCollection struct {
+	Kind string
+	ID   string
+	URL  string
+}
 
-		if v := q.Get("v"); v != "" && youtubeIDRegex.MatchString(v) {
-			return v, nil
-		}
+const (
+	CollectionPlaylist = "playlist"
+	CollectionChannel  = "channel"
+)
 
-		if strings.HasPrefix(u.Path, "/shorts/") {
-			id := strings.TrimPrefix(u.Path, "/shorts/")
-			if youtubeIDRegex.MatchString(id) {
-				return id, nil
+var (
+	listIDRegex    = regexp.MustCompile(`^[a-zA-Z0-9_-]{10,64}$`)
+	channelIDRegex = regexp.MustCompile(`^UC[a-zA-Z0-9_-]{22}$`)
+	channelName    = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,100}$`)
+)
+
+// generatedListPrefixes mark lists YouTube builds per viewer: mixes (RD),
+// upload mixes (UL), liked videos (LL) and watch later (WL). They are
+// private or endless, so they are never expanded.
+var generatedListPrefixes = []string{"RD", "UL", "LL", "WL"}
+
+// channelTabs are the tabs of a channel page; the listing always uses the
+// videos tab whichever one was linked.
+var channelTabs = map[string]bool{
+	"": true, "videos": true, "featured": true, "streams": true, "shorts": true,
+	"playlists": true, "community": true, "about": true,
+}
+
+// ParseYouTubeCollection recognises playlist links (/playlist?list=, or a
+// link that carries list= but no video) and channel links (/channel/UC...,
+// /@handle, /c/name and /user/name). A watch link with v= is always a single
+// video, even when it was opened from a playlist, and lists generated per
+// viewer (see generatedListPrefixes) are never expanded.
+func ParseYouTubeCollection(link string) (*YouTubeCollection, error) {
+	link = strings.TrimSpace(link)
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, errors.New("invalid url")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("not a youtube link")
+	}
+	switch strings.ToLower(u.Hostname()) {
+	case "youtube.com", "www.youtube.com", "m.youtube.com", "music.youtube.com":
+	default:
+		return nil, errors.New("not a youtube link")
+	}
+
+	q := u.Query()
+	if list := q.Get("list"); list != "" {
+		if q.Get("v") != "" {
+			return nil, errors.New("link points to a single video of the playlist")
+		}
+		for _, prefix := range generatedListPrefixes {
+			if strings.HasPrefix(list, prefix) {
+				return nil, errors.New("youtube mixes and personal lists are not expanded")
 			}
 		}
+		if !listIDRegex.MatchString(list) {
+			return nil, errors.New("invalid youtube playlist id")
+		}
+		return &YouTubeCollection{
+			Kind: CollectionPlaylist,
+			ID:   list,
+			URL:  "https://www.youtube.com/playlist?list=" + list,
+		}, nil
+	}
 
-		return "", errors.New("invalid youtube video id")
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	prefix, name, tab := "", "", ""
+	switch {
+	case strings.HasPrefix(parts[0], "@"):
+		name = parts[0]
+		if !channelName.MatchString(strings.TrimPrefix(name, "@")) {
+			return nil, errors.New("invalid youtube channel handle")
+		}
+		if len(parts) > 1 {
+			tab = parts[1]
+		}
+	case len(parts) >= 2 && (parts[0] == "channel" || parts[0] == "c" || parts[0] == "user"):
+		prefix, name = parts[0]+"/", parts[1]
+		if parts[0] == "channel" && !channelIDRegex.MatchString(name) {
+			return nil, errors.New("invalid youtube channel id")
+		}
+		if !channelName.MatchString(name) {
+			return nil, errors.New("invalid youtube channel name")
+		}
+		if len(parts) > 2 {
+			tab = parts[2]
+		}
+	default:
+		return nil, errors.New("not a youtube playlist or channel link")
 	}
+	if !channelTabs[tab] {
+		return nil, errors.New("not a youtube playlist or channel link")
+	}
+
+	return &YouTubeCollection{
+		Kind: CollectionChannel,
+		ID:   prefix + name,
+		URL:  "https://www.youtube.com/" + prefix + name + "/videos",
+	}, nil
+}
 
-	return "", errors.New("not a youtube link")
+// IsYouTubeCollection reports whether link should be expanded into one job
+// per video instead of being treated as a single video.
+func IsYouTubeCollection(link string) bool {
+	_, err := ParseYouTubeCollection(link)
+	return err == nil
 }
 
 var allowedMimeTypes = map[string]bool{
 	"application/pdf": true,
 	"text/plain":      true,
+	"text/markdown":   true,
+	"text/x-markdown": true,
+	"text/html":       true,
+	"application/rtf": true,
+	"text/rtf":        true,
+
+	"application/msword": true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   true,
+	"application/vnd.oasis.opendocument.text":                                   true,
+	"application/epub+zip":                                                      true,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": true,
+	"application/vnd.oasis.opendocument.presentation":                           true,
+
+	"text/csv":                 true,
+	"application/vnd.ms-excel": true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": true,
 
-	"audio/mpeg": true,
-	"audio/wav":  true,
-	"audio/mp4":  true,
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/tiff": true,
+
+	"audio/mpeg":  true,
+	"audio/wav":   true,
+	"audio/x-wav": true,
+	"audio/mp4":   true,
+	"audio/aac":   true,
+	"audio/ogg":   true,
+	"audio/opus":  true,
+	"audio/flac":  true,
+
+	"video/mp4":        true,
+	"video/x-matroska": true,
+	"video/webm":       true,
+	"video/quicktime":  true,
 }
 
 var allowedExtensions = map[string]bool{
-	".pdf":  true,
-	".doc":  true,
-	".docx": true,
-	".txt":  true,
-	".mp3":  true,
-	".wav":  true,
-	".m4a":  true,
+	".pdf":      true,
+	".doc":      true,
+	".docx":     true,
+	".odt":      true,
+	".epub":     true,
+	".pptx":     true,
+	".odp":      true,
+	".rtf":      true,
+	".md":       true,
+	".markdown": true,
+	".html":     true,
+	".htm":      true,
+	".txt":      true,
+	".csv":      true,
+	".xlsx":     true,
+	".png":      true,
+	".jpg":      true,
+	".jpeg":     true,
+	".tif":      true,
+	".tiff":     true,
+	".mp3":      true,
+	".wav":      true,
+	".m4a":      true,
+	".aac":      true,
+	".ogg":      true,
+	".opus":     true,
+	".flac":     true,
+	".mp4":      true,
+	".mkv":      true,
+	".webm":     true,
+	".mov":      true,
+}
+
+// extensionContentTypes gives the Content-Type of files that arrive without
+// one, such as archive entries. It covers every allowed extension.
+var extensionContentTypes = map[string]string{
+	".pdf":      "application/pdf",
+	".doc":      "application/msword",
+	".docx":     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".odt":      "application/vnd.oasis.opendocument.text",
+	".epub":     "application/epub+zip",
+	".pptx":     "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odp":      "application/vnd.oasis.opendocument.presentation",
+	".rtf":      "application/rtf",
+	".md":       "text/markdown",
+	".markdown": "text/markdown",
+	".html":     "text/html",
+	".htm":      "text/html",
+	".txt":      "text/plain",
+	".csv":      "text/csv",
+	".xlsx":     "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".png":      "image/png",
+	".jpg":      "image/jpeg",
+	".jpeg":     "image/jpeg",
+	".tif":      "image/tiff",
+	".tiff":     "image/tiff",
+	".mp3":      "audio/mpeg",
+	".wav":      "audio/wav",
+	".m4a":      "audio/mp4",
+	".aac":      "audio/aac",
+	".ogg":      "audio/ogg",
+	".opus":     "audio/opus",
+	".flac":     "audio/flac",
+	".mp4":      "video/mp4",
+	".mkv":      "video/x-matroska",
+	".webm":     "video/webm",
+	".mov":      "video/quicktime",
+}
+
+// ContentTypeByExtension returns the Content-Type an upload named filename
+// is validated with, or "" if its extension is not allowed.
+func ContentTypeByExtension(filename string) string {
+	return extensionContentTypes[strings.ToLower(filepath.Ext(filename))]
+}
+
+var videoExtensions = map[string]bool{
+	".mp4":  true,
+	".mkv":  true,
+	".webm": true,
+	".mov":  true,
 }
 
 func ValidateUploadedFile(header *multipart.FileHeader) error {
@@ -100,28 +388,73 @@ func ValidateUploadedFile(header *multipart.FileHeader) error {
 	return nil
 }
 
-func IsDoc(file multipart.File, filename string) bool {
-	buf := make([]byte, 512)
-	n, err := file.Read(buf)
+// IsDoc reports whether the upload is a document with a registered text
+// extractor, judged by its sniffed content rather than the client's claims.
+func IsDoc(file multipart.File, header *multipart.FileHeader) bool {
+	return extract.Supported(extract.DetectMIME(file, header.Size, header.Filename))
+}
+
+func IsVideo(filename string) bool {
+	return videoExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// trackingParams are dropped from URLs before they are used as cache keys.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "mc_cid": true, "mc_eid": true,
+}
+
+// NormalizeURL validates an http(s) link and returns the form used as its
+// cache key: lower-case scheme and host, no default port, no fragment, no
+// tracking parameters, and sorted query parameters.
+func NormalizeURL(link string) (string, error) {
+	if link == "" {
+		return "", errors.New("url is required")
+	}
+
+	u, err := url.Parse(strings.TrimSpace(link))
 	if err != nil {
-		return false
+		return "", errors.New("invalid url")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", errors.New("only http and https urls are supported")
+	}
+	if u.Hostname() == "" {
+		return "", errors.New("url has no host")
+	}
+	if u.User != nil {
+		return "", errors.New("urls with credentials are not supported")
 	}
 
-	if seeker, ok := file.(interface {
-		Seek(int64, int) (int64, error)
-	}); ok {
-		seeker.Seek(0, io.SeekStart)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		// IPv6 literals keep their brackets
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
 	}
 
-	contentType := http.DetectContentType(buf[:n])
-	if contentType == "application/pdf" || contentType == "text/plain" {
-		return true
+	q := u.Query()
+	for k := range q {
+		if strings.HasPrefix(strings.ToLower(k), "utm_") || trackingParams[strings.ToLower(k)] {
+			q.Del(k)
+		}
 	}
+	u.RawQuery = q.Encode()
 
-	ext := filepath.Ext(filename)
-	if ext == ".pdf" || ext == ".txt" {
-		return true
+	if u.Path == "" {
+		u.Path = "/"
 	}
+	u.Fragment = ""
+	u.RawFragment = ""
 
-	return false
+	return u.String(), nil
 }
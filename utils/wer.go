@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// WordErrorRate compares a transcript against a reference one: the word
+// substitutions, insertions and deletions needed to turn the reference into
+// the hypothesis, divided by the reference's word count. Case and punctuation
+// are ignored.
+func WordErrorRate(reference, hypothesis string) float64 {
+	ref, hyp := werWords(reference), werWords(hypothesis)
+	if len(ref) == 0 {
+		if len(hyp) == 0 {
+			return 0
+		}
+		return 1
+	}
+
+	// edit distance over words, keeping one row of the table
+	prev := make([]int, len(hyp)+1)
+	cur := make([]int, len(hyp)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ref); i++ {
+		cur[0] = i
+		for j := 1; j <= len(hyp); j++ {
+			cost := 1
+			if ref[i-1] == hyp[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return float64(prev[len(hyp)]) / float64(len(ref))
+}
+
+func werWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}